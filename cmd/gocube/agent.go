@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	gocube "github.com/rfielding/rustCube/cube"
+)
+
+/*
+  An agent is something that tries to solve the cube, like a program that
+  asks an AI. The agent command gives it the state, executes what it
+  proposes, and keeps going until the cube is solved or the budget of
+  moves or time runs out:
+
+    agent random                      turns faces at random, as a baseline
+    agent ./my-solver --verbose       runs a program that speaks json
+    agent -moves 100 -time 10s -mask cross random
+
+  A program gets a line of json with the state for every proposal it
+  makes, and answers with a line of json:
+
+    {"stickers": {...}, "facelets": "...", "mask": "cross",
+     "moves": 12, "moves_left": 88, "last": "r u", "error": ""}
+    {"expr": "[fr]3"}
+
+  error says why the last proposal did not parse or execute. An expr of ""
+  gives up. Every proposal is executed like a command, so p undoes them one
+  at a time.
+*/
+
+// AgentState is what an agent sees before it proposes
+type AgentState struct {
+	Stickers  map[string]string `json:"stickers"`
+	Facelets  string            `json:"facelets"`
+	Mask      string            `json:"mask,omitempty"`
+	Moves     int               `json:"moves"`
+	MovesLeft int               `json:"moves_left"`
+	Last      string            `json:"last,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+type Agent interface {
+	// Propose is the expression to execute next, or "" to give up
+	Propose(state AgentState) (string, error)
+}
+
+// RandomAgent turns a face at random, never the same face twice in a row
+type RandomAgent struct {
+	rnd  *rand.Rand
+	last string
+}
+
+func NewRandomAgent(seed int64) *RandomAgent {
+	return &RandomAgent{rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (agent *RandomAgent) Propose(state AgentState) (string, error) {
+	faces := []string{"u", "r", "f", "d", "l", "b"}
+	for {
+		face := faces[agent.rnd.Intn(len(faces))]
+		if face == agent.last {
+			continue
+		}
+		agent.last = face
+		return []string{face, "/" + face, face + "2"}[agent.rnd.Intn(3)], nil
+	}
+}
+
+// ProcessAgent is a program that reads states and writes proposals, a line
+// of json at a time
+type ProcessAgent struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+func NewProcessAgent(args []string) (*ProcessAgent, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &ProcessAgent{cmd: cmd, in: in, out: bufio.NewReader(out)}, nil
+}
+
+func (agent *ProcessAgent) Propose(state AgentState) (string, error) {
+	if err := json.NewEncoder(agent.in).Encode(state); err != nil {
+		return "", fmt.Errorf("the agent stopped reading: %s", err)
+	}
+	line, err := agent.out.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("the agent stopped answering: %s", err)
+	}
+	var answer struct {
+		Expr string `json:"expr"`
+	}
+	if err := json.Unmarshal([]byte(line), &answer); err != nil {
+		return "", fmt.Errorf("the agent answered %q: %s", strings.TrimSpace(line), err)
+	}
+	return answer.Expr, nil
+}
+
+// Close stops the program
+func (agent *ProcessAgent) Close() error {
+	agent.in.Close()
+	agent.cmd.Process.Kill()
+	return agent.cmd.Wait()
+}
+
+// AgentOptions are the budget of an agent, and what counts as solved
+type AgentOptions struct {
+	Moves int
+	Time  time.Duration
+	Mask  string
+	// Args are the agent, like random or a program and its arguments
+	Args []string
+}
+
+func parseAgentOptions(args []string) (AgentOptions, error) {
+	opts := AgentOptions{Moves: 200, Time: 30 * time.Second}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-moves", "-time", "-mask":
+			if i+1 == len(args) {
+				return opts, fmt.Errorf("%s needs a value", args[i])
+			}
+			value := args[i+1]
+			switch args[i] {
+			case "-moves":
+				n, err := strconv.Atoi(value)
+				if err != nil || n <= 0 {
+					return opts, fmt.Errorf("-moves should be a number of moves, not %s", value)
+				}
+				opts.Moves = n
+			case "-time":
+				d, err := time.ParseDuration(value)
+				if err != nil || d <= 0 {
+					return opts, fmt.Errorf("-time should be like 30s, not %s", value)
+				}
+				opts.Time = d
+			case "-mask":
+				opts.Mask = value
+			}
+			i++
+		default:
+			// the rest is the agent, and its own options
+			opts.Args = args[i:]
+			return opts, nil
+		}
+	}
+	return opts, fmt.Errorf("name an agent: random, or a program to run")
+}
+
+// NewAgent is random, or a program
+func NewAgent(args []string) (Agent, error) {
+	if len(args) == 1 && args[0] == "random" {
+		return NewRandomAgent(time.Now().UnixNano()), nil
+	}
+	return NewProcessAgent(args)
+}
+
+// RunAgent lets an agent make moves on the cube until it is solved, the
+// budget runs out, or it gives up. It says how it ended.
+func RunAgent(cube *gocube.Cube, agent Agent, opts AgentOptions) (string, error) {
+	var mask *gocube.Mask
+	if opts.Mask != "" {
+		masks, err := LoadMasks()
+		if err != nil {
+			return "", err
+		}
+		m, err := gocube.MaskOf(opts.Mask, strings.Fields(opts.Mask), masks)
+		if err != nil {
+			return "", err
+		}
+		mask = &m
+	}
+	solved := func() bool {
+		if mask == nil {
+			return cube.Solved()
+		}
+		state, err := cube.Tracking()
+		return err == nil && mask.Solved(state)
+	}
+
+	deadline := time.Now().Add(opts.Time)
+	state := AgentState{Mask: opts.Mask}
+	for n := 1; ; n++ {
+		if solved() {
+			return fmt.Sprintf("solved in %d moves", state.Moves), nil
+		}
+		if state.Moves >= opts.Moves {
+			return fmt.Sprintf("out of moves after %d", state.Moves), nil
+		}
+		left := time.Until(deadline)
+		if left <= 0 {
+			return fmt.Sprintf("out of time after %d moves", state.Moves), nil
+		}
+		state.Stickers, state.Facelets = gocube.CopyStickers(cube.Stickers), cube.Facelets()
+		state.MovesLeft = opts.Moves - state.Moves
+
+		// a program that hangs still runs out of time
+		type proposal struct {
+			expr string
+			err  error
+		}
+		answer := make(chan proposal, 1)
+		go func(state AgentState) {
+			expr, err := agent.Propose(state)
+			answer <- proposal{expr, err}
+		}(state)
+		var p proposal
+		select {
+		case p = <-answer:
+		case <-time.After(left):
+			return fmt.Sprintf("out of time after %d moves", state.Moves), nil
+		}
+		if p.err != nil {
+			return "", p.err
+		}
+		if strings.TrimSpace(p.expr) == "" {
+			return fmt.Sprintf("gave up after %d moves", state.Moves), nil
+		}
+
+		state.Last, state.Error = p.expr, ""
+		node, err := cube.Parse(p.expr)
+		if err != nil {
+			state.Error = fmt.Sprintf("parse error: %s", err)
+			fmt.Printf("%3d: %s -- %s\n", n, p.expr, state.Error)
+			continue
+		}
+		moves, err := cube.ExecuteCommand(node)
+		if err != nil {
+			cube.Pop()
+			state.Error = fmt.Sprintf("execute error: %s", err)
+			fmt.Printf("%3d: %s -- %s\n", n, p.expr, state.Error)
+			continue
+		}
+		count := len(strings.Fields(moves))
+		if state.Moves+count > opts.Moves {
+			// it does not fit in the budget, so it does not happen
+			cube.Pop()
+			return fmt.Sprintf("out of moves after %d, %s needs %d more", state.Moves, p.expr, count), nil
+		}
+		state.Moves += count
+		fmt.Printf("%3d: %s -- %d moves, %d in all\n", n, p.expr, count, state.Moves)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+/*
+  -batch runs lines from stdin, and -script runs them from a file, with the
+  same commands as the prompt:
+
+    echo "n(fdrfdbl)5 ru" | gocube -batch -print moves,period
+    gocube -script scramble.cube
+
+  Nothing is drawn, and help and metrics are left out. -print says what to
+  print: the moves that every expression made as it runs, and at the end
+  the state of the current cube and its period (how many times the moves
+  that got it there can be made before it is back to where it started).
+  Blank lines are skipped, and -- starts a comment.
+
+  The first line that fails stops it, with its line number on stderr, and
+  the exit status is 1.
+*/
+
+var BatchFlag = flag.Bool("batch", false, "run lines from stdin without drawing")
+var ScriptFlag = flag.String("script", "", "run lines from a file without drawing")
+var PrintFlag = flag.String("print", "state", "what -batch and -script print: state, moves, period")
+
+// BatchOutputs are what -print can ask for
+var BatchOutputs = []string{"state", "moves", "period"}
+
+// parsePrint reads -print, like state,moves
+func parsePrint(s string) (map[string]bool, error) {
+	outputs := make(map[string]bool)
+	for _, w := range strings.Split(s, ",") {
+		w = strings.TrimSpace(w)
+		if w == "" {
+			continue
+		}
+		if !slices.Contains(BatchOutputs, w) {
+			return nil, fmt.Errorf("can not print %s. choose from: %s", w, strings.Join(BatchOutputs, ","))
+		}
+		outputs[w] = true
+	}
+	return outputs, nil
+}
+
+// Batch runs every line from in, and is the exit status
+func Batch(name string, in io.Reader) int {
+	outputs, err := parsePrint(*PrintFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+	rdr := bufio.NewReader(in)
+	repl := NewRepl(&Editor{In: rdr})
+	repl.Batch = true
+	repl.Print = outputs
+	for n := 1; ; n++ {
+		line, err := rdr.ReadString('\n')
+		if err != nil && line == "" {
+			if err != io.EOF {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+				return 1
+			}
+			break
+		}
+		line, _, _ = strings.Cut(line, "--")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		quit := repl.Run(line)
+		if repl.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s: %s\n", name, n, line, repl.Err)
+			return 1
+		}
+		if quit {
+			break
+		}
+	}
+	cube := repl.Slots.Cube()
+	if outputs["state"] {
+		fmt.Printf("state: %s\n", cube.Facelets())
+	}
+	if outputs["period"] {
+		period, err := cube.Period()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: period: %s\n", name, err)
+			return 1
+		}
+		fmt.Printf("period: %d\n", period)
+	}
+	return 0
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gocube "github.com/rfielding/rustCube/cube"
+)
+
+/*
+  The prompt keeps its settings in files in the gocube dir of the config
+  dir, like ~/.config/gocube:
+
+    masks      masks of your own, in the format of the preset masks
+    colors     the scheme, mode and overlay, and custom face colors
+    sessions/  the display mask of every -session
+    history    the lines typed at the prompt
+*/
+
+// configPath is where a config file with this name lives
+func configPath(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "gocube", name)
+}
+
+// LoadMasks gets the preset masks, and the ones in the masks file
+func LoadMasks() (map[string]gocube.Mask, error) {
+	masks, err := gocube.Presets()
+	if err != nil {
+		return masks, err
+	}
+	path := configPath("masks")
+	text, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return masks, nil
+	}
+	if err != nil {
+		return masks, err
+	}
+	if err := gocube.ParseMasks(string(text), masks); err != nil {
+		return masks, fmt.Errorf("%s: %s", path, err)
+	}
+	return masks, nil
+}
+
+// LoadColors uses the colors file, if there is one
+func LoadColors() error {
+	path := configPath("colors")
+	text, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	config, custom, err := style.ParseColors(string(text))
+	if err == nil {
+		err = style.SetColors(config, custom)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return nil
+}
+
+// SaveColors writes the config to the colors file
+func SaveColors() (string, error) {
+	path := configPath("colors")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return path, err
+	}
+	return path, os.WriteFile(path, []byte(style.ColorsText()), 0644)
+}
+
+// DetectAnsi turns colors off for NO_COLOR, and for output that is not a terminal
+func DetectAnsi() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// SetDisplayMask sets the display mask from mask names and stickers, or
+// clears it for off, and saves it in the session
+func SetDisplayMask(words []string) error {
+	if len(words) == 1 && words[0] == "off" {
+		style.Mask = nil
+		return saveSession()
+	}
+	masks, err := LoadMasks()
+	if err != nil {
+		return err
+	}
+	mask, err := gocube.MaskOf(strings.Join(words, " "), words, masks)
+	if err != nil {
+		return err
+	}
+	style.Mask = &mask
+	return saveSession()
+}
+
+/*
+  A session keeps the display mask between runs, so that a drill can
+  start with its mask set. Sessions are files in the sessions dir of the
+  config dir, and -session picks one:
+
+    mask: f2l
+*/
+
+func sessionPath() string {
+	return filepath.Join(configPath("sessions"), *Session)
+}
+
+func saveSession() error {
+	text := ""
+	if style.Mask != nil {
+		text = fmt.Sprintf("mask: %s\n", style.Mask.Name)
+	}
+	path := sessionPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(text), 0644)
+}
+
+// LoadSession sets the display mask that the session saved
+func LoadSession() error {
+	path := sessionPath()
+	text, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(text), "\n") {
+		name, value, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(name) != "mask" {
+			continue
+		}
+		masks, err := LoadMasks()
+		if err != nil {
+			return err
+		}
+		words := strings.Fields(value)
+		mask, err := gocube.MaskOf(strings.Join(words, " "), words, masks)
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		style.Mask = &mask
+	}
+	return nil
+}
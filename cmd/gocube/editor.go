@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+  The editor reads a line in the terminal, so that rlwrap is not needed:
+
+    ← → ctrl-a ctrl-e     move in the line
+    ↑ ↓ ctrl-p ctrl-n     history, which is kept in the history file
+    ctrl-r                search back in history, again for older matches
+    tab                   complete commands, sticker and piece names, masks and cubes
+    ctrl-k ctrl-u ctrl-w  delete to the end, to the start, a word back
+    ctrl-d                quit on an empty line
+
+  The bracket next to the cursor is shown with its pair, and brackets
+  without a pair are underlined, since unbalanced groups are the most
+  common parse error. When stdin is not a terminal, lines are read as
+  they are.
+*/
+
+// Commands are the words that start REPL commands, for completion
+var Commands = []string{
+	"agent", "bridge", "colors", "copy", "diff", "drop", "export", "find3", "gif", "group", "help",
+	"highlight", "labels", "mask", "metrics", "play", "quit", "solve", "test", "trace", "tree", "tui", "use",
+}
+
+// the most lines of history that are kept
+const historySize = 1000
+
+type Editor struct {
+	In      *bufio.Reader
+	History []string
+	// Names lists what tab can complete to
+	Names func() []string
+}
+
+func historyPath() string {
+	return configPath("history")
+}
+
+// NewEditor reads keys from in, and loads the history file
+func NewEditor(in *bufio.Reader) *Editor {
+	ed := &Editor{In: in}
+	text, err := os.ReadFile(historyPath())
+	if err == nil {
+		for _, line := range strings.Split(string(text), "\n") {
+			if line != "" {
+				ed.History = append(ed.History, line)
+			}
+		}
+	}
+	// the file only grows in remember, so it is cut back here
+	if len(ed.History) > historySize {
+		ed.History = ed.History[len(ed.History)-historySize:]
+		os.WriteFile(historyPath(), []byte(strings.Join(ed.History, "\n")+"\n"), 0644)
+	}
+	return ed
+}
+
+// remember adds a line to history, and to the history file
+func (ed *Editor) remember(line string) {
+	if line == "" || len(ed.History) > 0 && ed.History[len(ed.History)-1] == line {
+		return
+	}
+	ed.History = append(ed.History, line)
+	path := historyPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+// ReadLine reads a line after a prompt. It is io.EOF for ctrl-d on an empty line.
+func (ed *Editor) ReadLine(prompt string) (string, error) {
+	restore, err := keyMode()
+	if err != nil {
+		fmt.Printf("%s", prompt)
+		line, err := ed.In.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+	defer restore()
+	line, err := ed.edit(prompt)
+	fmt.Printf("\n")
+	if err == nil {
+		ed.remember(strings.TrimSpace(line))
+	}
+	return strings.TrimSpace(line), err
+}
+
+// bracketPairs finds the pair of every bracket, and the brackets without one
+func bracketPairs(buf []rune) (map[int]int, map[int]bool) {
+	pairs := make(map[int]int)
+	unmatched := make(map[int]bool)
+	closes := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	stack := make([]int, 0)
+	for i, r := range buf {
+		switch r {
+		case '(', '[', '{':
+			stack = append(stack, i)
+		case ')', ']', '}':
+			if len(stack) == 0 || buf[stack[len(stack)-1]] != closes[r] {
+				unmatched[i] = true
+				continue
+			}
+			open := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			pairs[open], pairs[i] = i, open
+		}
+	}
+	for _, i := range stack {
+		unmatched[i] = true
+	}
+	return pairs, unmatched
+}
+
+// renderLine writes the line with the bracket at the cursor and its pair
+// in reverse, and brackets without a pair underlined, or red with colors
+func renderLine(buf []rune, pos int) string {
+	pairs, unmatched := bracketPairs(buf)
+	at := -1
+	for _, i := range []int{pos, pos - 1} {
+		if _, ok := pairs[i]; ok && i >= 0 {
+			at = i
+			break
+		}
+	}
+	var b strings.Builder
+	for i, r := range buf {
+		switch {
+		case at >= 0 && (i == at || i == pairs[at]):
+			fmt.Fprintf(&b, "\u001b[7m%c\u001b[0m", r)
+		case unmatched[i] && style.Ansi:
+			fmt.Fprintf(&b, "\u001b[1;4;31m%c\u001b[0m", r)
+		case unmatched[i]:
+			fmt.Fprintf(&b, "\u001b[4m%c\u001b[0m", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func redraw(prompt string, buf []rune, pos int) {
+	fmt.Printf("\r%s%s\u001b[K", prompt, renderLine(buf, pos))
+	if back := len(buf) - pos; back > 0 {
+		fmt.Printf("\u001b[%dD", back)
+	}
+}
+
+// escape reads the rest of an escape sequence, as the name of the key
+func (ed *Editor) escape() string {
+	b, err := ed.In.ReadByte()
+	if err != nil || b != '[' && b != 'O' {
+		return ""
+	}
+	seq := ""
+	for {
+		c, err := ed.In.ReadByte()
+		if err != nil {
+			return ""
+		}
+		seq += string(c)
+		if c >= 0x40 && c <= 0x7e {
+			break
+		}
+	}
+	switch seq {
+	case "A":
+		return "up"
+	case "B":
+		return "down"
+	case "C":
+		return "right"
+	case "D":
+		return "left"
+	case "H", "1~":
+		return "home"
+	case "F", "4~":
+		return "end"
+	case "3~":
+		return "delete"
+	}
+	return ""
+}
+
+func isWordRune(r rune) bool {
+	return r == '-' || r == '=' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// complete finishes the word before pos, or lists what it could be
+func (ed *Editor) complete(buf []rune, pos int) ([]rune, int) {
+	start := pos
+	for start > 0 && isWordRune(buf[start-1]) {
+		start--
+	}
+	word := string(buf[start:pos])
+	seen := make(map[string]bool)
+	matches := make([]string, 0)
+	if ed.Names != nil {
+		for _, name := range ed.Names() {
+			if strings.HasPrefix(name, word) && !seen[name] {
+				seen[name] = true
+				matches = append(matches, name)
+			}
+		}
+	}
+	sort.Strings(matches)
+	if len(matches) == 0 {
+		fmt.Printf("\a")
+		return buf, pos
+	}
+	common := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(matches) == 1 {
+		common += " "
+	}
+	if common == word {
+		fmt.Printf("\n%s\n", strings.Join(matches, " "))
+		return buf, pos
+	}
+	insert := []rune(common[len(word):])
+	out := append(append(append([]rune{}, buf[:pos]...), insert...), buf[pos:]...)
+	return out, pos + len(insert)
+}
+
+// search is ctrl-r. It gives the line that it found, and whether to run it now.
+// When nothing is found, the line is left as it was.
+func (ed *Editor) search(buf []rune) ([]rune, bool) {
+	query := ""
+	from := len(ed.History) - 1
+	for {
+		match := -1
+		for i := from; i >= 0; i-- {
+			if strings.Contains(ed.History[i], query) {
+				match = i
+				break
+			}
+		}
+		found := ""
+		label := "reverse-i-search"
+		line := buf
+		if match < 0 {
+			label = "failed reverse-i-search"
+		} else {
+			found = ed.History[match]
+			line = []rune(found)
+		}
+		fmt.Printf("\r(%s)`%s': %s\u001b[K", label, query, found)
+		b, err := ed.In.ReadByte()
+		if err != nil {
+			return buf, false
+		}
+		switch {
+		case b == 18:
+			if match > 0 {
+				from = match - 1
+			}
+		case b == 7:
+			return buf, false
+		case b == 127 || b == 8:
+			if query != "" {
+				_, size := utf8.DecodeLastRuneInString(query)
+				query = query[:len(query)-size]
+			}
+			from = len(ed.History) - 1
+		case b == '\n' || b == '\r':
+			return line, match >= 0
+		case b == 27:
+			ed.escape()
+			return line, false
+		case b >= 32:
+			query += string(b)
+		default:
+			return line, false
+		}
+	}
+}
+
+// edit reads keys until enter
+func (ed *Editor) edit(prompt string) (string, error) {
+	buf := make([]rune, 0)
+	pos := 0
+	at := len(ed.History)
+	draft := buf
+	for {
+		redraw(prompt, buf, pos)
+		b, err := ed.In.ReadByte()
+		if err != nil {
+			return string(buf), err
+		}
+		key := ""
+		switch b {
+		case 27:
+			key = ed.escape()
+		case 1:
+			key = "home"
+		case 5:
+			key = "end"
+		case 2:
+			key = "left"
+		case 6:
+			key = "right"
+		case 16:
+			key = "up"
+		case 14:
+			key = "down"
+		}
+		switch {
+		case b == '\n' || b == '\r':
+			pos = len(buf)
+			redraw(prompt, buf, pos)
+			return string(buf), nil
+		case b == 4 && len(buf) == 0:
+			return "", io.EOF
+		case b == 4 || key == "delete":
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+		case b == 127 || b == 8:
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case b == 11:
+			buf = buf[:pos]
+		case b == 21:
+			buf = append([]rune{}, buf[pos:]...)
+			pos = 0
+		case b == 23:
+			start := pos
+			for start > 0 && buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start-1] != ' ' {
+				start--
+			}
+			buf = append(buf[:start], buf[pos:]...)
+			pos = start
+		case b == 9:
+			buf, pos = ed.complete(buf, pos)
+		case b == 18:
+			found, run := ed.search(buf)
+			buf, pos = found, len(found)
+			if run {
+				redraw(prompt, buf, pos)
+				return string(buf), nil
+			}
+		case key == "home":
+			pos = 0
+		case key == "end":
+			pos = len(buf)
+		case key == "left":
+			if pos > 0 {
+				pos--
+			}
+		case key == "right":
+			if pos < len(buf) {
+				pos++
+			}
+		case key == "up":
+			if at > 0 {
+				if at == len(ed.History) {
+					draft = buf
+				}
+				at--
+				buf = []rune(ed.History[at])
+				pos = len(buf)
+			}
+		case key == "down":
+			if at < len(ed.History) {
+				at++
+				if at == len(ed.History) {
+					buf = draft
+				} else {
+					buf = []rune(ed.History[at])
+				}
+				pos = len(buf)
+			}
+		case b >= 32 && b != 127:
+			r := rune(b)
+			if b >= 0x80 {
+				ed.In.UnreadByte()
+				r, _, _ = ed.In.ReadRune()
+			}
+			buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+			pos++
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"io"
+	"strings"
+
+	gocube "github.com/rfielding/rustCube/cube"
+)
+
+/*
+  -json reads a request per line from stdin, and writes the response to it
+  as a line on stdout. The requests and responses are the ones in the cube
+  package, and check can use the masks in the masks file.
+*/
+
+var JSONFlag = flag.Bool("json", false, "read json requests from stdin, and write json responses")
+
+// respond is Respond, with the masks file for check
+func respond(cube *gocube.Cube, req gocube.Request) (*gocube.Cube, gocube.Response) {
+	var masks map[string]gocube.Mask
+	if req.Op == "check" && strings.TrimSpace(req.Mask) != "" {
+		var err error
+		masks, err = LoadMasks()
+		if err != nil {
+			resp := gocube.Response{ID: req.ID, Op: req.Op, Error: &gocube.ResponseError{Kind: "mask", Message: err.Error()}}
+			return cube, cube.WithState(resp, nil)
+		}
+	}
+	return cube.Respond(req, masks)
+}
+
+// ServeJSON answers a line of json from in with a line of json to out, until in ends
+func ServeJSON(in io.Reader, out io.Writer) error {
+	cube := gocube.NewCube()
+	rdr := bufio.NewReader(in)
+	enc := json.NewEncoder(out)
+	for {
+		line, err := rdr.ReadString('\n')
+		if err != nil && line == "" {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var req gocube.Request
+		var resp gocube.Response
+		if jerr := json.Unmarshal([]byte(line), &req); jerr != nil {
+			resp = cube.WithState(gocube.Response{Error: &gocube.ResponseError{Kind: "request", Message: jerr.Error()}}, nil)
+		} else {
+			cube, resp = respond(cube, req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	gocube "github.com/rfielding/rustCube/cube"
+)
+
+// when the width of the terminal can not be found, like in a pipe
+const defaultWidth = 160
+
+// terminalWidth is COLUMNS, or what stty says, in characters
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	stty := exec.Command("stty", "size")
+	stty.Stdin = os.Stdin
+	out, err := stty.Output()
+	if err == nil {
+		fields := strings.Fields(string(out))
+		if len(fields) == 2 {
+			if n, err := strconv.Atoi(fields[1]); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return defaultWidth
+}
+
+// Draw cubes side-by-side, as many as fit, with captions under them if there are any
+func Draw(cmd string, repeats int, cubes []*gocube.Cube, captions []string) {
+	if err := style.Draw(os.Stdout, cubes, captions, terminalWidth()); err != nil {
+		printRed(fmt.Sprintf("draw error: %s", err))
+	}
+	fmt.Printf("cmd: %s x %d\n", cmd, repeats)
+}
+
+// DrawIso draws the front and opposite views of cubes, side by side
+func DrawIso(cmd string, repeats int, cubes []*gocube.Cube, captions []string) {
+	fmt.Println()
+	if err := style.DrawIso(os.Stdout, cubes, captions, terminalWidth()); err != nil {
+		printRed(fmt.Sprintf("draw error: %s", err))
+	}
+	fmt.Println()
+	fmt.Printf("cmd: %s x %d\n", cmd, repeats)
+}
+
+// sttyMode sets the terminal with stty, and restore puts it back. It
+// fails when stdin is not a terminal.
+func sttyMode(args ...string) (func(), error) {
+	stty := func(args ...string) (string, error) {
+		c := exec.Command("stty", args...)
+		c.Stdin = os.Stdin
+		out, err := c.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return func() {}, err
+	}
+	if _, err := stty(args...); err != nil {
+		return func() {}, err
+	}
+	return func() { stty(saved) }, nil
+}
+
+// rawMode makes the terminal pass keys through one at a time, without
+// echo, and makes reads give up after a tenth of a second so that a
+// caller can animate while it waits for keys. restore puts it back.
+func rawMode() (func(), error) {
+	return sttyMode("-icanon", "-echo", "min", "0", "time", "1")
+}
+
+// keyMode is like rawMode, but reads wait for a key
+func keyMode() (func(), error) {
+	return sttyMode("-icanon", "-echo", "min", "1", "time", "0")
+}
+
+// clearScreen starts a frame of an animation at the top of the terminal
+func clearScreen() {
+	if style.Ansi {
+		fmt.Printf("\u001b[H\u001b[2J")
+	} else {
+		fmt.Printf("\n\n")
+	}
+}
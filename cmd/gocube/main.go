@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	gocube "github.com/rfielding/rustCube/cube"
+)
+
+/*
+  gocube is the prompt for the cube package: it draws cubes in the
+  terminal and runs the commands that are typed at it. The flags pick
+  something else to do instead, like -batch, -json or -serve.
+
+  The cube model, the parser and Execute are in the cube package, so that
+  other tools can import them without any of this.
+*/
+
+var PostTest = flag.Bool("postTest", false, "post test on start")
+var ColorFlag = flag.String("color", "auto", "ansi colors: auto, always or never")
+var Session = flag.String("session", "default", "session that keeps the display mask")
+
+// style is how the prompt draws cubes, which the a, highlight, labels,
+// mask and colors commands change
+var style = gocube.NewStyle()
+
+// UseIso draws the isometric view instead of the net
+var UseIso = false
+
+func main() {
+	flag.Parse()
+	switch *ColorFlag {
+	case "always":
+		style.Ansi = true
+	case "never":
+		style.Ansi = false
+	default:
+		style.Ansi = DetectAnsi()
+	}
+	if err := LoadColors(); err != nil {
+		fmt.Fprintf(os.Stderr, "colors error: %s\n", err)
+	}
+	if err := LoadSession(); err != nil {
+		fmt.Fprintf(os.Stderr, "session error: %s\n", err)
+	}
+	switch {
+	case *PostTest:
+		if err := postTest(os.Stdout); err != nil {
+			printRed(fmt.Sprintf("post test failed: %s", err))
+			os.Exit(1)
+		}
+	case *ScriptFlag != "":
+		f, err := os.Open(*ScriptFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(2)
+		}
+		status := Batch(*ScriptFlag, f)
+		f.Close()
+		os.Exit(status)
+	case *JSONFlag:
+		if err := ServeJSON(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	case *ServeFlag != "":
+		if err := Serve(*ServeFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	case *WatchFlag != "":
+		if err := Watch(*WatchFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	case *BatchFlag:
+		os.Exit(Batch("stdin", os.Stdin))
+	default:
+		Loop()
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	gocube "github.com/rfielding/rustCube/cube"
+)
+
+/*
+  play animates an expression one quarter turn at a time, from the cube as
+  it is now, without changing it. It shows the node of the expression that
+  made each turn, which is a way to debug how negation, commutators and
+  reflections nest:
+
+    space   pause and play
+    n or →  step forward
+    b or ←  step back
+    + and - faster and slower
+    0 and $ go to the start and the end
+    q       stop playing
+
+  Without a terminal to read keys from one at a time, it starts paused,
+  and the keys are read a line at a time.
+*/
+
+// playStep is one quarter turn, and the stickers after it
+type playStep struct {
+	Path     []int
+	Face     string
+	Dir      int
+	Stickers map[string]string
+}
+
+// playSteps traces what Execute turns, and splits it into quarter turns
+func playSteps(cube *gocube.Cube, node gocube.Node) ([]playStep, error) {
+	type event struct {
+		path []int
+		face string
+		turn int
+	}
+	events := make([]event, 0)
+	scratch := cube.Clone()
+	scratch.Trace = func(path []int, face string, turn int) {
+		events = append(events, event{append([]int{}, path...), face, turn})
+	}
+	if _, err := scratch.Execute(node, 0, 0, 0, 0, 0); err != nil {
+		return nil, err
+	}
+
+	replay := cube.Clone()
+	steps := make([]playStep, 0)
+	for _, e := range events {
+		dir, n := 1, e.turn
+		if n < 0 {
+			dir, n = -1, -n
+		}
+		for i := 0; i < n; i++ {
+			if err := replay.Turn(e.face, dir); err != nil {
+				return nil, err
+			}
+			steps = append(steps, playStep{Path: e.path, Face: e.face, Dir: dir, Stickers: gocube.CopyStickers(replay.Stickers)})
+		}
+	}
+	return steps, nil
+}
+
+func (step playStep) String() string {
+	if step.Dir < 0 {
+		return "/" + step.Face
+	}
+	return step.Face
+}
+
+// playMark shows the running node, or the current step of the filmstrip
+func playMark(s string) string {
+	if style.Ansi {
+		return "\u001b[7m" + s + "\u001b[0m"
+	}
+	return "»" + s + "«"
+}
+
+// filmstrip lists the quarter turns around the one at, as many as fit
+func filmstrip(steps []playStep, at int) string {
+	if len(steps) == 0 {
+		return "(no turns)"
+	}
+	half := (terminalWidth()/4 - 2) / 2
+	if half < 2 {
+		half = 2
+	}
+	from, to := at-1-half, at-1+half
+	if from < 0 {
+		from = 0
+	}
+	if to >= len(steps) {
+		to = len(steps) - 1
+	}
+	words := make([]string, 0)
+	if from > 0 {
+		words = append(words, "...")
+	}
+	for i := from; i <= to; i++ {
+		w := steps[i].String()
+		if i == at-1 {
+			w = playMark(w)
+		}
+		words = append(words, w)
+	}
+	if to < len(steps)-1 {
+		words = append(words, "...")
+	}
+	return strings.Join(words, " ")
+}
+
+// playKeys reads keys, with arrows as the words right and left
+func playKeys(buf []byte) []string {
+	keys := make([]string, 0)
+	for i := 0; i < len(buf); i++ {
+		if buf[i] == 0x1b && i+2 < len(buf) && buf[i+1] == '[' {
+			switch buf[i+2] {
+			case 'C':
+				keys = append(keys, "right")
+			case 'D':
+				keys = append(keys, "left")
+			}
+			i += 2
+			continue
+		}
+		keys = append(keys, string(buf[i:i+1]))
+	}
+	return keys
+}
+
+// Play animates an expression from the cube, reading keys from keys
+func Play(cube *gocube.Cube, cmd string, node gocube.Node, keys *bufio.Reader) error {
+	steps, err := playSteps(cube, node)
+	if err != nil {
+		return err
+	}
+	restore, rawErr := rawMode()
+	defer restore()
+
+	at := 0
+	playing := rawErr == nil
+	delay := 500 * time.Millisecond
+	last := time.Now()
+	draw := func() {
+		clearScreen()
+		frame := cube.Clone()
+		frame.Before = nil
+		running := "(start)"
+		if at > 0 {
+			prev := cube.Stickers
+			if at > 1 {
+				prev = steps[at-2].Stickers
+			}
+			before := gocube.StateOf(prev)
+			frame.Before = &before
+			frame.Stickers = gocube.CopyStickers(steps[at-1].Stickers)
+			running = node.PrintMarked(steps[at-1].Path, playMark)
+		}
+		state := "paused"
+		if playing {
+			state = "playing"
+		}
+		Draw(cmd, 1, []*gocube.Cube{frame}, []string{fmt.Sprintf("quarter turn %d/%d", at, len(steps))})
+		fmt.Printf("running: %s\n", running)
+		fmt.Printf("turns:   %s\n", filmstrip(steps, at))
+		fmt.Printf("%s, %s per quarter turn\n", state, delay)
+		fmt.Printf("space pause  n/→ step  b/← back  +/- speed  0/$ start/end  q quit\n")
+	}
+
+	draw()
+	buf := make([]byte, 16)
+	for {
+		var n int
+		var err error
+		if rawErr == nil {
+			n, err = keys.Read(buf)
+			if err == io.EOF {
+				// a read that gives up is a read of nothing, which a file calls EOF
+				err = nil
+			}
+		} else {
+			// a line at a time, so that the lines after q are left for Loop
+			var line string
+			line, err = keys.ReadString('\n')
+			buf = []byte(strings.TrimSpace(line))
+			n = len(buf)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		dirty := false
+		if n == 0 {
+			// the read gave up waiting, so it is time to animate
+			if playing && time.Since(last) >= delay {
+				last = time.Now()
+				if at < len(steps) {
+					at++
+				} else {
+					playing = false
+				}
+				dirty = true
+			}
+		}
+		for _, k := range playKeys(buf[:n]) {
+			dirty = true
+			switch k {
+			case "q", "\x1b", "\x04":
+				return nil
+			case " ":
+				if at == len(steps) {
+					at = 0
+				}
+				playing = !playing
+				last = time.Now()
+			case "n", "right":
+				playing = false
+				if at < len(steps) {
+					at++
+				}
+			case "b", "left":
+				playing = false
+				if at > 0 {
+					at--
+				}
+			case "+", "=":
+				if delay > 50*time.Millisecond {
+					delay /= 2
+				}
+			case "-":
+				if delay < 4*time.Second {
+					delay *= 2
+				}
+			case "0":
+				at = 0
+			case "$":
+				at = len(steps)
+			default:
+				dirty = false
+			}
+		}
+		if dirty {
+			draw()
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	gocube "github.com/rfielding/rustCube/cube"
+)
+
+/*
+  -postTest runs the SelfTest of the cube package, and then checks what
+  the prompt adds on top of it. Only things that run without a terminal
+  or a network are checked, so that it works anywhere.
+*/
+
+func postTest(w io.Writer) error {
+	if err := gocube.SelfTest(w); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "running cli post test\n")
+
+	// play splits turns into quarter turns, and knows which node made them
+	fmt.Fprintf(w, "checkPlay: [f u2]\n")
+	cube := gocube.NewCube()
+	node, err := cube.Parse("[f u2]")
+	if err != nil {
+		return fmt.Errorf("play error: %s", err)
+	}
+	steps, err := playSteps(cube, node)
+	if err != nil {
+		return fmt.Errorf("play error: %s", err)
+	}
+	turns := make([]string, 0, len(steps))
+	for _, step := range steps {
+		turns = append(turns, step.String())
+	}
+	if got := strings.Join(turns, " "); got != "f u u /f /u /u" {
+		return fmt.Errorf("play [f u2] should step f u u /f /u /u, not %s", got)
+	}
+	if path := steps[1].Path; len(path) == 0 || path[len(path)-1] != 1 {
+		return fmt.Errorf("play [f u2] should say u2 made the second step, not %v", path)
+	}
+	if !cube.Solved() {
+		return fmt.Errorf("play should not change the cube")
+	}
+	if err := cube.Apply("[f u2]"); err != nil {
+		return fmt.Errorf("play error: %s", err)
+	}
+	if gocube.StateOf(steps[len(steps)-1].Stickers) != cube.State() {
+		return fmt.Errorf("the last step of play [f u2] should be the cube that [f u2] makes")
+	}
+
+	// -print takes a list of outputs, and refuses ones it does not have
+	fmt.Fprintf(w, "checkBatch: -print\n")
+	if outputs, err := parsePrint(" moves,period,"); err != nil || len(outputs) != 2 || !outputs["moves"] || !outputs["period"] {
+		return fmt.Errorf("-print moves,period should print moves and period, not %v (%v)", outputs, err)
+	}
+	for _, bad := range []string{"colours", "state,stickers"} {
+		if _, err := parsePrint(bad); err == nil {
+			return fmt.Errorf("-print %s should be an error", bad)
+		}
+	}
+	// the state and period that -batch prints at the end
+	for _, b := range []struct {
+		expr     string
+		facelets string
+		period   int
+	}{
+		{"r", "uufuufuuf rrrrrrrrr ffdffdffd ddbddbddb lllllllll ubbubbubb", 4},
+		{"[fr]", "", 6},
+		{"ru", "", 105},
+	} {
+		fmt.Fprintf(w, "checkBatch: state and period of %s\n", b.expr)
+		cube := gocube.NewCube()
+		if err := cube.Apply(b.expr); err != nil {
+			return fmt.Errorf("batch error: %s", err)
+		}
+		if b.facelets != "" && cube.Facelets() != b.facelets {
+			return fmt.Errorf("state of %s should be %s, not %s", b.expr, b.facelets, cube.Facelets())
+		}
+		if period, err := cube.Period(); err != nil || period != b.period {
+			return fmt.Errorf("period of %s should be %d, not %d (%v)", b.expr, b.period, period, err)
+		}
+	}
+	// a line that fails is the error that stops -batch
+	fmt.Fprintf(w, "checkBatch: lines that fail\n")
+	repl := NewRepl(&Editor{In: bufio.NewReader(strings.NewReader(""))})
+	repl.Batch, repl.Print = true, map[string]bool{}
+	for _, line := range []struct {
+		text string
+		fail bool
+	}{{"r", false}, {"p", false}, {"p", true}, {"((", true}, {"export svg", true}} {
+		repl.Err = nil
+		repl.Run(line.text)
+		if (repl.Err != nil) != line.fail {
+			return fmt.Errorf("batch line %s should fail: %t, but the error is %v", line.text, line.fail, repl.Err)
+		}
+	}
+
+	// a -json session, one response per request line, in the same order.
+	// Each is summed up as: id, whether it was ok, the error kind, moves, undo,
+	// with - for no id and no error
+	fmt.Fprintf(w, "checkJSON: a session of requests\n")
+	session := `{"id": 1, "op": "parse", "expr": "[fr]"}
+{"id": "two", "op": "execute", "expr": "[fr]"}
+not json
+
+{"op": "execute", "expr": "(("}
+{"op": "undo"}
+{"op": "undo"}
+{"op": "spin"}
+`
+	var out strings.Builder
+	if err := ServeJSON(strings.NewReader(session), &out); err != nil {
+		return fmt.Errorf("json error: %s", err)
+	}
+	summary := make([]string, 0)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var resp gocube.Response
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			return fmt.Errorf("json response is not json: %s", line)
+		}
+		id, kind := "-", "-"
+		if len(resp.ID) > 0 {
+			id = string(resp.ID)
+		}
+		if resp.Error != nil {
+			kind = resp.Error.Kind
+		}
+		summary = append(summary, fmt.Sprintf("%s %t %s %s %d", id, resp.OK, kind, strings.Join(resp.Moves, ","), resp.Undo))
+	}
+	want := []string{
+		`1 true -  0`,
+		`"two" true - f,r,/f,/r 1`,
+		`- false request  1`,
+		`- false parse  1`,
+		`- true -  0`,
+		`- false undo  0`,
+		`- false request  0`,
+	}
+	if strings.Join(summary, "\n") != strings.Join(want, "\n") {
+		return fmt.Errorf("json session should answer:\n%s\nnot:\n%s", strings.Join(want, "\n"), strings.Join(summary, "\n"))
+	}
+
+	// eval says whether the sides of an equation leave the same cube
+	for _, e := range []struct {
+		expr  string
+		equal bool
+		fail  bool
+	}{{"[fr]6 == ()", true, false}, {"[fr]3 == ()", false, false}, {"r == ((", false, true}, {"r", false, true}} {
+		fmt.Fprintf(w, "checkServe: eval %s\n", e.expr)
+		equal, _, rerr := Equal(e.expr)
+		if (rerr != nil) != e.fail || equal != e.equal {
+			return fmt.Errorf("eval %s should be equal: %t and fail: %t, not %t and %v", e.expr, e.equal, e.fail, equal, rerr)
+		}
+	}
+	// the api, without listening on anything
+	handler := NewServer().Handler()
+	call := func(method, path, body string, header http.Header) (int, string) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for k := range header {
+			req.Header.Set(k, header.Get(k))
+		}
+		handler.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+	for _, c := range []struct {
+		method, path, body string
+		status             int
+		has                string
+	}{
+		{"POST", "/api/sessions", `{"name": "t"}`, http.StatusCreated, `"id":"t"`},
+		{"POST", "/api/sessions", `{"name": "t"}`, http.StatusConflict, "already exists"},
+		{"POST", "/api/sessions/t/execute", `{"expr": "[fr]3"}`, http.StatusOK, `"undo":1`},
+		{"POST", "/api/sessions/t/execute", `{"expr": "(("}`, http.StatusBadRequest, `"kind":"parse"`},
+		{"POST", "/api/sessions/t/execute", `{"expr": "(fdrfdbl)999999999"}`, http.StatusBadRequest, "more than"},
+		{"POST", "/api/sessions/t/execute", `{"expr": "` + strings.Repeat("u", maxRequestBytes) + `"}`, http.StatusBadRequest, "too large"},
+		{"GET", "/api/sessions/t/svg?colors=japanese", "", http.StatusOK, "<svg"},
+		{"GET", "/api/sessions/t/svg?colors=pink", "", http.StatusBadRequest, "pink"},
+		{"POST", "/api/sessions/t/undo", "", http.StatusOK, `"solved":true`},
+		{"POST", "/api/sessions/t/undo", "", http.StatusConflict, `"kind":"undo"`},
+		{"POST", "/api/eval", `{"expr": "[fr]6 == ()"}`, http.StatusOK, `"equal":true`},
+		{"DELETE", "/api/sessions/t", "", http.StatusOK, `"ok":true`},
+		{"GET", "/api/sessions/t", "", http.StatusNotFound, "no session"},
+	} {
+		fmt.Fprintf(w, "checkServe: %s %s\n", c.method, c.path)
+		status, body := call(c.method, c.path, c.body, nil)
+		if status != c.status || !strings.Contains(body, c.has) {
+			return fmt.Errorf("%s %s should be %d with %s, not %d: %.200s", c.method, c.path, c.status, c.has, status, body)
+		}
+	}
+	// another site in a browser can not post a form to a session, or watch it
+	fmt.Fprintf(w, "checkServe: requests from other pages\n")
+	call("POST", "/api/sessions", `{"name": "o"}`, nil)
+	if status, _ := call("POST", "/api/sessions/o/execute", `{"expr": "r"}`, http.Header{"Content-Type": {"text/plain"}}); status != http.StatusUnsupportedMediaType {
+		return fmt.Errorf("a text/plain execute should be %d, not %d", http.StatusUnsupportedMediaType, status)
+	}
+	upgrade := http.Header{
+		"Connection": {"Upgrade"}, "Upgrade": {"websocket"}, "Sec-Websocket-Version": {"13"},
+		"Sec-Websocket-Key": {"dGhlIHNhbXBsZSBub25jZQ=="}, "Origin": {"http://elsewhere.example"},
+	}
+	if status, _ := call("GET", "/api/sessions/o/ws", "", upgrade); status != http.StatusForbidden {
+		return fmt.Errorf("a websocket from another origin should be %d, not %d", http.StatusForbidden, status)
+	}
+
+	// the web page is at / only, and draws the cube the way it asks the api to
+	fmt.Fprintf(w, "checkWeb: the page and what it asks for\n")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		return fmt.Errorf("GET / should be an html page, not %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	for _, has := range []string{`<form id="form">`, `id="expr"`, `"Content-Type": "application/json"`, `api("/ws")`} {
+		if !strings.Contains(rec.Body.String(), has) {
+			return fmt.Errorf("the web page should have %s", has)
+		}
+	}
+	if status, _ := call("GET", "/index.html", "", nil); status != http.StatusNotFound {
+		return fmt.Errorf("GET /index.html should be not found, not %d", status)
+	}
+	call("POST", "/api/sessions", `{"name": "page"}`, nil)
+	for _, view := range []string{"net", "iso"} {
+		path := "/api/sessions/page/svg?view=" + view + "&at=" + strings.ReplaceAll(gocube.NewCube().Facelets(), " ", "%20")
+		if status, body := call("GET", path, "", nil); status != http.StatusOK || !strings.HasPrefix(body, "<svg") {
+			return fmt.Errorf("GET %s should be an svg, not %d: %.200s", path, status, body)
+		}
+	}
+
+	// the handshake key from the example in the websocket RFC
+	fmt.Fprintf(w, "checkWebSocket: handshake and frames\n")
+	if got := wsAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		return fmt.Errorf("websocket accept key should be s3pPLMBiTxaQ9kYGzzhZRbK+xOo=, not %s", got)
+	}
+	// frames both ways, masked from the client, over a pipe in memory
+	clientConn, serverConn := net.Pipe()
+	client := &WebSocket{conn: clientConn, rw: bufio.NewReadWriter(bufio.NewReader(clientConn), bufio.NewWriter(clientConn)), client: true}
+	server := &WebSocket{conn: serverConn, rw: bufio.NewReadWriter(bufio.NewReader(serverConn), bufio.NewWriter(serverConn))}
+	long := gocube.Request{Op: "execute", Expr: strings.Repeat("u", 70000)}
+	for _, pair := range []struct{ from, to *WebSocket }{{client, server}, {server, client}} {
+		for _, req := range []gocube.Request{{Op: "state"}, {Op: "execute", Expr: "[fr]3"}, long} {
+			sent := make(chan error, 1)
+			go func() { sent <- pair.from.WriteJSON(req) }()
+			message, err := pair.to.ReadMessage()
+			if err == nil {
+				err = <-sent
+			}
+			var got gocube.Request
+			if err == nil {
+				err = json.Unmarshal(message, &got)
+			}
+			if err != nil || got.Op != req.Op || got.Expr != req.Expr {
+				return fmt.Errorf("websocket should carry %s %.20s, not %s %.20s (%v)", req.Op, req.Expr, got.Op, got.Expr, err)
+			}
+		}
+	}
+	clientConn.Close()
+	serverConn.Close()
+	// watchers get every change to a session in order, and are closed with it
+	fmt.Fprintf(w, "checkWebSocket: watchers\n")
+	_, sess, err := NewServer().Create("t")
+	if err != nil {
+		return fmt.Errorf("watch error: %s", err)
+	}
+	first, events, stop := sess.watch()
+	if !first.Solved {
+		return fmt.Errorf("a watcher should start with the state of the session")
+	}
+	for _, req := range []gocube.Request{{Op: "execute", Expr: "r"}, {Op: "state"}, {Op: "execute", Expr: "(("}, {Op: "undo"}} {
+		sess.do(req)
+	}
+	for _, op := range []string{"execute", "undo"} {
+		select {
+		case resp := <-events:
+			if resp.Op != op {
+				return fmt.Errorf("a watcher should be told %s, not %s", op, resp.Op)
+			}
+		default:
+			return fmt.Errorf("a watcher should be told %s", op)
+		}
+	}
+	select {
+	case resp := <-events:
+		return fmt.Errorf("a watcher should only be told about changes, not %s", resp.Op)
+	default:
+	}
+	sess.close()
+	if _, open := <-events; open {
+		return fmt.Errorf("a watcher should be closed when its session is")
+	}
+	stop()
+
+	// a random agent with seed 4 turns /r first, which solves r
+	for _, a := range []struct {
+		seed   int64
+		moves  int
+		result string
+	}{{4, 5, "solved in 1 moves"}, {1, 5, "out of moves after"}} {
+		fmt.Fprintf(w, "checkAgent: random seed %d on r in %d moves\n", a.seed, a.moves)
+		cube := gocube.NewCube()
+		if err := cube.Apply("r"); err != nil {
+			return fmt.Errorf("agent error: %s", err)
+		}
+		result, err := RunAgent(cube, NewRandomAgent(a.seed), AgentOptions{Moves: a.moves, Time: 10 * time.Second})
+		if err != nil {
+			return fmt.Errorf("agent error: %s", err)
+		}
+		if !strings.HasPrefix(result, a.result) {
+			return fmt.Errorf("random agent with seed %d should end %s, not %s", a.seed, a.result, result)
+		}
+		// every proposal of a random agent is one move, after the r
+		if len(cube.History)-1 > a.moves {
+			return fmt.Errorf("random agent made %d moves, more than %d", len(cube.History)-1, a.moves)
+		}
+	}
+
+	fmt.Fprintf(w, "cli post test complete\n\n")
+	return nil
+}
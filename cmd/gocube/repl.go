@@ -0,0 +1,663 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	gocube "github.com/rfielding/rustCube/cube"
+)
+
+func facesString(cube *gocube.Cube, upperCase bool) string {
+	if !style.Ansi {
+		return " U  R  F  D  L  B"
+	}
+	uc := func(s string) string {
+		if upperCase {
+			return strings.ToUpper(s)
+		}
+		return s
+	}
+
+	// use the middle piece location to find the color
+	// for u r f d l b
+	return fmt.Sprintf(" %s  %s  %s  %s  %s  %s",
+		style.FaceColored(cube.Stickers["u"], uc("u")),
+		style.FaceColored(cube.Stickers["r"], uc("r")),
+		style.FaceColored(cube.Stickers["f"], uc("f")),
+		style.FaceColored(cube.Stickers["d"], uc("d")),
+		style.FaceColored(cube.Stickers["l"], uc("l")),
+		style.FaceColored(cube.Stickers["b"], uc("b")),
+	)
+}
+
+func colorStr(color int, s string) string {
+	if !style.Ansi {
+		return s
+	}
+	return fmt.Sprintf("\u001b[1;%dm%s\u001b[0m", color, s)
+}
+
+func Help(cube *gocube.Cube) {
+	printRed("-----BEGIN HELP-----\n")
+	fmt.Printf("arrows for history, ctrl-r to search it, tab to complete commands and stickers.\n")
+	fmt.Printf("from cmd/gocube at: %s\n", colorStr(32, "https://github.com/rfielding/rustCube"))
+	fmt.Printf("conventions: Up Right Front Down Left Back\n")
+	fmt.Printf("reverse a turn with '/', like: /u\n")
+	fmt.Printf("commutators have period 6: ((ru)/(ur))6 => ()\n")
+	fmt.Printf("negate: /(rf) = /f /r\n")
+	fmt.Printf("commutator: [fr] => f r /f /r\n")
+	fmt.Printf("conjugate: {ru} => r u /r\n")
+	fmt.Printf("mirror across an axis: negate all faces and swap names on axis. ex: x[[fr]3u]=[[/f/l]3/u] \n")
+	fmt.Println()
+	for i := range gocube.EqTest {
+		for j := 0; j < len(gocube.EqTest[i]); j++ {
+			if j == 0 {
+				fmt.Printf("%s ", colorStr(34, "example:"))
+			} else {
+				fmt.Printf(" == ")
+			}
+			v := gocube.EqTest[i][j]
+			if v == "" {
+				v = "()"
+			}
+			fmt.Printf("%s", v)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%s nru         -- start from new cube, then ru\n", colorStr(34, "example:"))
+	fmt.Printf("%s n(fdrfdbl)5 -- for a deterministic scramble, you can find in history with ctrl-r\n", colorStr(34, "example:"))
+	fmt.Println()
+	fmt.Printf("help: ? or h\n")
+	fmt.Printf("new cube: n\n")
+	fmt.Printf("toggle ansi colors: a\n")
+	fmt.Printf("toggle isometric 3d view: i\n")
+	fmt.Printf("mark stickers the last command changed: highlight [border|blink|mark|none]\n")
+	fmt.Printf("grey out stickers that are not in masks: mask f2l  -- or mask off. the session keeps it\n")
+	fmt.Printf("name the stickers by location, or by the piece that is there: labels [keys|pieces|none]\n")
+	fmt.Printf("stickers that differ between the two cubes: diff\n")
+	fmt.Printf("color scheme, escapes and color-blind overlay: colors japanese 256 letters  -- colors save keeps them\n")
+	fmt.Printf("test: run tests on expressions\n")
+	fmt.Printf("quit: q\n")
+	fmt.Println()
+	fmt.Printf("turn a face: %s\n", facesString(cube, false))
+	fmt.Printf("turn cube:   %s\n", facesString(cube, true))
+	fmt.Printf("pop move off history (undo): p\n")
+	fmt.Printf("swap with the other cube: s\n")
+	fmt.Printf("named cubes: use c, copy a b, drop c  -- * is the current cube, + the other one\n")
+	fmt.Printf("find a {setup [X Y]} that 3-cycles pieces: find3 uf ur ub\n")
+	fmt.Printf("shortest moves to the other cube: bridge [-gen ru] [-depth 10] [apply]\n")
+	fmt.Printf("solve part of the cube: solve [-gen ru] [-depth 14] cross|f2l-fr|eo|lastlayer|stickers [apply]\n")
+	fmt.Printf("save a picture: export svg cube.svg [both] [caption]  -- or export iso for 3d\n")
+	fmt.Printf("  pictures and gifs are in the rgb colors of the scheme: orange where colors 16 shows magenta\n")
+	fmt.Printf("how an expression parses, and what each node does when it runs: tree {f {ru}}, trace /{f {ru}}  -- -json for json\n")
+	fmt.Printf("full screen, where a key turns a face: tui\n")
+	fmt.Printf("step through moves from this cube, without changing it: play {f {ru}}\n")
+	fmt.Printf("replay moves from this cube, a frame per quarter turn: gif [[fd]2 u] twist.gif\n")
+	fmt.Printf("count moves in htm qtm stm etm: metrics [[fr]3 u]  -- or just metrics for the examples\n")
+	fmt.Printf("let an agent solve it: agent [-moves 200] [-time 30s] [-mask cross] random|<program that speaks json>\n")
+	fmt.Printf("size of the group some moves make: group <r,u>  -- -gen takes the same list, like -gen [fr],u\n")
+	fmt.Printf("startup test flag: -postTest\n")
+	fmt.Printf("session flag, to keep a mask for a drill: -session f2ldrill\n")
+	fmt.Printf("color flag: -color auto|always|never  -- auto is off for NO_COLOR and pipes\n")
+	printRed("-----END HELP-----\n")
+}
+
+// print a message in red if ansi
+func printRed(msg string) {
+	if style.Ansi {
+		fmt.Printf("\u001b[1;31m%s\u001b[0m\n", msg)
+	} else {
+		fmt.Printf("%s\n", msg)
+	}
+}
+
+// Repl is the state of the prompt between lines, so that -batch can run
+// lines with the same commands
+type Repl struct {
+	Slots   *Slots
+	Editor  *Editor
+	Cmd     string
+	Repeats int
+	PrevCmd string
+	// Batch leaves out help and chatter, and keeps the last error
+	Batch bool
+	Print map[string]bool
+	Err   error
+}
+
+// fail reports a command that did not work, in red with a blank line
+// after it, or as the error that stops -batch
+func (repl *Repl) fail(msg string) {
+	repl.Err = fmt.Errorf("%s", msg)
+	if !repl.Batch {
+		printRed(msg)
+		fmt.Println()
+	}
+}
+
+func NewRepl(editor *Editor) *Repl {
+	return &Repl{Slots: NewSlots(), Editor: editor}
+}
+
+func Loop() {
+	repl := NewRepl(NewEditor(bufio.NewReader(os.Stdin)))
+	slots := repl.Slots
+	repl.Editor.Names = func() []string {
+		names := append([]string{}, Commands...)
+		names = append(names, gocube.StickerKeys...)
+		masks, _ := LoadMasks()
+		names = append(names, strings.Fields(gocube.MaskNames(masks))...)
+		return append(names, strings.Fields(slots.Names())...)
+	}
+	Help(slots.Cube())
+
+	// loop to get and anlyze a line and draw the screen
+	for {
+		if UseIso {
+			DrawIso(repl.Cmd, repl.Repeats, slots.Cubes(), slots.Captions())
+		} else {
+			Draw(repl.Cmd, repl.Repeats, slots.Cubes(), slots.Captions())
+		}
+
+		cmd, err := repl.Editor.ReadLine("\u25B6 ")
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Printf("Error reading input\n")
+			continue
+		}
+		if repl.Run(cmd) {
+			break
+		}
+	}
+}
+
+// Run runs a line, and is true when it was a quit
+func (repl *Repl) Run(cmd string) bool {
+	slots := repl.Slots
+	cube, cube2 := slots.Cube(), slots.OtherCube()
+	repl.Err = nil
+	// Draw shows the command as it ran, after n and repeats change it
+	defer func() { repl.Cmd = cmd }()
+
+	if cmd == "a" {
+		style.Ansi = !style.Ansi
+		return false
+	}
+
+	if cmd == "i" {
+		UseIso = !UseIso
+		return false
+	}
+
+	if cmd == "q" || cmd == "quit" || cmd == "exit" {
+		return true
+	}
+
+	if cmd == "test" {
+		if err := postTest(os.Stdout); err != nil {
+			repl.fail(fmt.Sprintf("test error: %s", err))
+		}
+		return false
+	}
+
+	if cmd == "?" || cmd == "h" || cmd == "help" {
+		Help(cube)
+		if !repl.Batch {
+			repl.Editor.In.ReadString('\n')
+		}
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "find3") {
+		args := strings.Fields(cmd)
+		if len(args) != 4 {
+			repl.fail("usage: find3 <a> <b> <c>  -- like: find3 uf ur ub")
+			return false
+		}
+		found, flattened, err := cube.Find3(args[1], args[2], args[3])
+		if err != nil {
+			repl.fail(fmt.Sprintf("find3 error: %s", err))
+			return false
+		}
+		fmt.Printf("3-cycle: %s\n", found)
+		fmt.Printf("executes as: %s\n", flattened)
+		fmt.Println()
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "bridge") {
+		opts, err := parseSearchOptions(strings.Fields(cmd)[1:], 10)
+		if err == nil && (len(opts.Args) > 1 || len(opts.Args) == 1 && opts.Args[0] != "apply") {
+			err = fmt.Errorf("unexpected: %s", strings.Join(opts.Args, " "))
+		}
+		if err != nil {
+			repl.fail(fmt.Sprintf("%s\nusage: bridge [-gen ru] [-depth 10] [apply]", err))
+			return false
+		}
+		found, err := cube.Bridge(cube2, opts.Gen, opts.Depth)
+		if err != nil {
+			repl.fail(fmt.Sprintf("bridge error: %s", err))
+			return false
+		}
+		if found == "" {
+			fmt.Printf("the cubes are already the same\n\n")
+			return false
+		}
+		fmt.Printf("bridge to the other cube: %s\n", found)
+		if len(opts.Args) == 1 {
+			if err := cube.Apply(found); err != nil {
+				repl.fail(fmt.Sprintf("bridge error: %s", err))
+				return false
+			}
+			fmt.Printf("applied. undo with p\n")
+		}
+		fmt.Println()
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "export") {
+		args := strings.Fields(cmd)[1:]
+		if len(args) < 2 || args[0] != "svg" && args[0] != "iso" {
+			repl.fail("usage: export svg|iso <file> [both] [caption]")
+			return false
+		}
+		cubes := []*gocube.Cube{cube}
+		caption := ""
+		for _, a := range args[2:] {
+			switch a {
+			case "both":
+				cubes = append(cubes, cube2)
+			case "caption":
+				caption = fmt.Sprintf("%s x %d", repl.PrevCmd, repl.Repeats)
+			default:
+				repl.fail(fmt.Sprintf("unexpected: %s\nusage: export svg|iso <file> [both] [caption]", a))
+				return false
+			}
+		}
+		f, err := os.Create(args[1])
+		if err == nil {
+			if args[0] == "iso" {
+				err = style.IsoSVG(f, cubes, caption)
+			} else {
+				err = style.DrawSVG(f, cubes, caption)
+			}
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			repl.fail(fmt.Sprintf("export error: %s", err))
+			return false
+		}
+		fmt.Printf("wrote %s\n\n", args[1])
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "gif") {
+		args := strings.Fields(cmd)[1:]
+		if len(args) < 2 {
+			repl.fail("usage: gif <expr> <file>  -- like: gif [[fd]2 u] twist.gif")
+			return false
+		}
+		file := args[len(args)-1]
+		nodes, err := cube.Parse(strings.Join(args[:len(args)-1], " "))
+		if err != nil {
+			repl.fail(fmt.Sprintf("parse error: %s", err))
+			return false
+		}
+		// made in memory first, so that a gif that fails leaves no file
+		var b bytes.Buffer
+		err = style.GIF(&b, cube, nodes)
+		if err == nil {
+			err = os.WriteFile(file, b.Bytes(), 0644)
+		}
+		if err != nil {
+			repl.fail(fmt.Sprintf("gif error: %s", err))
+			return false
+		}
+		fmt.Printf("wrote %s for %s\n\n", file, nodes.Print())
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "metrics") {
+		exprs := []string{strings.TrimSpace(strings.TrimPrefix(cmd, "metrics"))}
+		if exprs[0] == "" {
+			// compare all of the examples
+			exprs = exprs[:0]
+			for i := range gocube.EqTest {
+				exprs = append(exprs, gocube.StrippedComment(gocube.EqTest[i][0]))
+			}
+		}
+		for _, expr := range exprs {
+			raw, simplified, simple, err := cube.Measure(expr)
+			if err != nil {
+				repl.fail(fmt.Sprintf("metrics error on %s: %s", expr, err))
+				continue
+			}
+			fmt.Printf("%s\n", colorStr(34, expr))
+			fmt.Printf("  raw:        %s\n", raw)
+			fmt.Printf("  simplified: %s -- %s\n", simplified, simple)
+		}
+		fmt.Println()
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "highlight") {
+		args := strings.Fields(cmd)[1:]
+		if len(args) == 0 {
+			// toggle, like a and i
+			if style.Highlight == "none" {
+				args = []string{"border"}
+			} else {
+				args = []string{"none"}
+			}
+		}
+		if len(args) != 1 || !slices.Contains(gocube.HighlightStyles, args[0]) {
+			repl.fail(fmt.Sprintf("usage: highlight [%s]", strings.Join(gocube.HighlightStyles, "|")))
+			return false
+		}
+		style.Highlight = args[0]
+		return false
+	}
+
+	if cmd == "tui" {
+		if err := TUI(slots, repl.Editor); err != nil {
+			repl.fail(fmt.Sprintf("tui error: %s", err))
+		}
+		repl.Repeats = 0
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "tree ") || strings.HasPrefix(cmd, "trace ") {
+		name, expr, _ := strings.Cut(cmd, " ")
+		expr = strings.TrimSpace(expr)
+		asJSON := strings.HasPrefix(expr, "-json")
+		if asJSON {
+			expr = strings.TrimSpace(strings.TrimPrefix(expr, "-json"))
+		}
+		node, err := cube.Parse(expr)
+		out := ""
+		switch {
+		case err != nil:
+		case name == "tree" && asJSON:
+			out, err = gocube.TreeJSON(node)
+		case name == "tree":
+			out = gocube.TreeText(node)
+		default:
+			var visits []gocube.Visit
+			visits, err = cube.TraceVisits(node)
+			if err == nil && asJSON {
+				out, err = gocube.TraceJSON(visits)
+			} else if err == nil {
+				out = gocube.TraceText(visits)
+			}
+		}
+		if err != nil {
+			repl.fail(fmt.Sprintf("%s error: %s", name, err))
+			return false
+		}
+		fmt.Printf("%s\n", strings.TrimRight(out, "\n"))
+		fmt.Println()
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "play ") {
+		expr := strings.TrimSpace(strings.TrimPrefix(cmd, "play "))
+		node, err := cube.Parse(expr)
+		if err == nil && repl.Batch {
+			err = fmt.Errorf("play needs the prompt, to read keys")
+		}
+		if err == nil {
+			err = Play(cube, expr, node, repl.Editor.In)
+		}
+		if err != nil {
+			repl.fail(fmt.Sprintf("play error: %s", err))
+		}
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "use ") || strings.HasPrefix(cmd, "copy ") || strings.HasPrefix(cmd, "drop ") {
+		args := strings.Fields(cmd)
+		var err error
+		switch {
+		case args[0] == "use" && len(args) == 2:
+			slots.Use(args[1])
+			repl.Repeats = 0
+		case args[0] == "copy" && len(args) == 3:
+			err = slots.Copy(args[1], args[2])
+		case args[0] == "drop" && len(args) == 2:
+			err = slots.Drop(args[1])
+		default:
+			err = fmt.Errorf("usage: use c, copy a b, drop c")
+		}
+		if err != nil {
+			repl.fail(fmt.Sprintf("%s", err))
+		}
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "mask") {
+		args := strings.Fields(cmd)[1:]
+		if len(args) == 0 {
+			masks, _ := LoadMasks()
+			repl.fail(fmt.Sprintf("usage: mask off|<masks and stickers>  -- masks: %s", gocube.MaskNames(masks)))
+			return false
+		}
+		if err := SetDisplayMask(args); err != nil {
+			repl.fail(fmt.Sprintf("mask error: %s", err))
+		}
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "labels") {
+		args := strings.Fields(cmd)[1:]
+		if len(args) == 0 {
+			if style.Labels == "none" {
+				args = []string{"keys"}
+			} else {
+				args = []string{"none"}
+			}
+		}
+		if len(args) != 1 || !slices.Contains(gocube.LabelModes, args[0]) {
+			repl.fail(fmt.Sprintf("usage: labels [%s]", strings.Join(gocube.LabelModes, "|")))
+			return false
+		}
+		style.Labels = args[0]
+		return false
+	}
+
+	if cmd == "diff" {
+		fmt.Printf("%s\n", style.DiffString(cube, cube2))
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "colors") {
+		args := strings.Fields(cmd)[1:]
+		if len(args) == 1 && args[0] == "save" {
+			path, err := SaveColors()
+			if err != nil {
+				repl.fail(fmt.Sprintf("colors error: %s", err))
+				return false
+			}
+			fmt.Printf("wrote %s\n", path)
+			return false
+		}
+		if err := style.UseColorWords(args); err != nil {
+			repl.fail(fmt.Sprintf("%s\nusage: colors [%s] [%s] [%s]  -- or: colors save",
+				err, strings.Join(strings.Fields(style.SchemeNames()), "|"), strings.Join(gocube.ColorModes, "|"), strings.Join(gocube.ColorOverlays, "|")))
+			return false
+		}
+		fmt.Printf("%s\n", style.ColorsText())
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "agent") {
+		opts, err := parseAgentOptions(strings.Fields(cmd)[1:])
+		var agent Agent
+		if err == nil {
+			agent, err = NewAgent(opts.Args)
+		}
+		if err != nil {
+			repl.fail(fmt.Sprintf("%s\nusage: agent [-moves 200] [-time 30s] [-mask cross] random|<program and args>", err))
+			return false
+		}
+		if closer, ok := agent.(io.Closer); ok {
+			defer closer.Close()
+		}
+		result, err := RunAgent(cube, agent, opts)
+		if err != nil {
+			repl.fail(fmt.Sprintf("agent error: %s", err))
+			return false
+		}
+		fmt.Printf("%s. undo with p, a proposal at a time\n\n", result)
+		repl.Repeats = 0
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "group") {
+		exprs, err := gocube.Generators(strings.TrimPrefix(cmd, "group"))
+		if err != nil {
+			repl.fail(fmt.Sprintf("%s\nusage: group <r,u>  -- or: group ru, group [fr] u", err))
+			return false
+		}
+		order, err := cube.GroupOrder(exprs)
+		if err != nil {
+			repl.fail(fmt.Sprintf("group error: %s", err))
+			return false
+		}
+		fmt.Printf("order of <%s>: %s\n\n", strings.Join(exprs, ", "), withCommas(order))
+		return false
+	}
+
+	if strings.HasPrefix(cmd, "solve") {
+		opts, err := parseSearchOptions(strings.Fields(cmd)[1:], 14)
+		apply := len(opts.Args) > 0 && opts.Args[len(opts.Args)-1] == "apply"
+		if apply {
+			opts.Args = opts.Args[:len(opts.Args)-1]
+		}
+		masks, merr := LoadMasks()
+		if err == nil {
+			err = merr
+		}
+		if err == nil && len(opts.Args) == 0 {
+			err = fmt.Errorf("name a mask, or list stickers. masks: %s", gocube.MaskNames(masks))
+		}
+		var mask gocube.Mask
+		if err == nil {
+			mask, err = gocube.MaskOf(strings.Join(opts.Args, " "), opts.Args, masks)
+		}
+		if err != nil {
+			repl.fail(fmt.Sprintf("%s\nusage: solve [-gen ru] [-depth 14] <mask or stickers> [apply]", err))
+			return false
+		}
+		found, err := cube.Solve(mask, opts.Gen, opts.Depth)
+		if err != nil {
+			repl.fail(fmt.Sprintf("solve error: %s", err))
+			return false
+		}
+		if found == "" {
+			fmt.Printf("%s is already solved\n\n", mask.Name)
+			return false
+		}
+		fmt.Printf("solve %s: %s\n", mask.Name, found)
+		if apply {
+			if err := cube.Apply(found); err != nil {
+				repl.fail(fmt.Sprintf("solve error: %s", err))
+				return false
+			}
+			fmt.Printf("applied. undo with p\n")
+		}
+		fmt.Println()
+		return false
+	}
+
+	if cmd == repl.PrevCmd || cmd == "" {
+		if cmd == "" {
+			cmd = repl.PrevCmd
+		}
+		repl.Repeats = repl.Repeats + 1
+	} else {
+		repl.Repeats = 1
+	}
+	repl.PrevCmd = cmd
+
+	if cmd == "p" {
+		didPop := cube.Pop()
+		if !didPop {
+			repl.fail("nothing to undo!")
+		}
+		if !repl.Batch {
+			fmt.Printf("stack size: %d\n", len(cube.History))
+		}
+		return false
+	}
+
+	if len(cmd) > 0 && cmd[0] == 'n' {
+		cube = gocube.NewCube()
+		slots.SetCube(cube)
+		cmd = cmd[1:]
+	}
+
+	if cmd == "n" {
+		cube = gocube.NewCube()
+		slots.SetCube(cube)
+		repl.Repeats = 0
+		return false
+	}
+
+	if cmd == "s" {
+		slots.Swap()
+		repl.Repeats = 0
+		return false
+	}
+
+	nodes, err := cube.Parse(cmd)
+	if err != nil {
+		if repl.Batch {
+			repl.fail(fmt.Sprintf("parse error: %s", err))
+			return false
+		}
+		Help(cube)
+		msg := fmt.Sprintf("parse error. see help above: %s", err)
+		repl.fail(msg)
+		return false
+	}
+
+	if !repl.Batch {
+		fmt.Printf("parsed as: %s\n", nodes.Print())
+	}
+	flattened, err := cube.ExecuteCommand(nodes)
+	if err != nil {
+		if repl.Batch {
+			repl.fail(fmt.Sprintf("execute error: %s", err))
+			return false
+		}
+		Help(cube)
+		msg := fmt.Sprintf("execute error. see help above: %s", err)
+		repl.fail(msg)
+		return false
+	}
+	slots.Ran(cmd, repl.Repeats)
+	if repl.Batch {
+		if repl.Print["moves"] {
+			fmt.Printf("moves: %s\n", strings.TrimSpace(flattened))
+		}
+		return false
+	}
+	fmt.Printf("executed moves: %s\n", flattened)
+	if raw, simplified, simple, err := gocube.MeasureMoves(flattened); err == nil {
+		fmt.Printf("metrics: %s\n", raw)
+		fmt.Printf("simplified: %s\n", simple)
+		fmt.Printf("simplified metrics: %s\n", simplified)
+	}
+	fmt.Println()
+	fmt.Println()
+	return false
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+/*
+  The options of the commands that search, like bridge and solve, and how
+  their answers are printed.
+*/
+
+// searchOptions are the options that searching commands share:
+//
+//	-gen ru    only turn r and u
+//	-gen [fr],u  turn [fr] and u, which are generators
+//	-depth 8   give up after this many moves
+type searchOptions struct {
+	Gen   string
+	Depth int
+	// Args are the words that are not options
+	Args []string
+}
+
+func parseSearchOptions(args []string, depth int) (searchOptions, error) {
+	opts := searchOptions{Depth: depth}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-gen", "-depth":
+			if i+1 == len(args) {
+				return opts, fmt.Errorf("%s needs a value", args[i])
+			}
+			if args[i] == "-gen" {
+				opts.Gen = args[i+1]
+			} else {
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil || n < 0 {
+					return opts, fmt.Errorf("-depth should be a number of moves, not %s", args[i+1])
+				}
+				opts.Depth = n
+			}
+			i++
+		default:
+			if strings.HasPrefix(args[i], "-") {
+				return opts, fmt.Errorf("unknown option: %s", args[i])
+			}
+			opts.Args = append(opts.Args, args[i])
+		}
+	}
+	return opts, nil
+}
+
+// withCommas writes big numbers like 73,483,200
+func withCommas(n *big.Int) string {
+	s := n.String()
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
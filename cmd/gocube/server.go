@@ -0,0 +1,448 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	gocube "github.com/rfielding/rustCube/cube"
+)
+
+/*
+  -serve runs an http api, for notebooks and small web tools:
+
+    POST   /api/sessions               make a cube, {"name": "demo"} to name it
+    GET    /api/sessions               list them
+    GET    /api/sessions/{id}          its state
+    DELETE /api/sessions/{id}          remove it
+    POST   /api/sessions/{id}/execute  {"expr": "[fr]3"}
+    POST   /api/sessions/{id}/undo
+    POST   /api/sessions/{id}/reset
+    GET    /api/sessions/{id}/svg      the net, like export svg, or ?view=iso,
+                                       in ?colors=japanese like the colors command
+    GET    /api/sessions/{id}/ws       a websocket that watches it
+    POST   /api/eval                   {"expr": "[fr]6 == ()"}
+
+  The responses are json like -json gives, and a request that fails has
+  ok false, and a status that is not 200. Every session has its own lock,
+  so clients can use different cubes at once, and the requests to one cube
+  happen one at a time.
+
+  A request body can be 64k at most, and an expression can make at most
+  100000 turns, so that one client can not keep a session locked.
+
+  An address without a host, like :8080, is only served on 127.0.0.1.
+  Other pages in a browser can not use the api: a body has to say it is
+  application/json, which a form can not send, and a websocket has to come
+  from a page of this server.
+
+  A websocket gets the state of its session when it connects, and then
+  every execute, undo and reset that changes it, from whoever made it, in
+  the order that they happened. It can send requests like -json takes, too.
+  The answer to a request that changes the cube comes like everyone else
+  gets it, and the answer to one that does not comes just to the sender.
+*/
+
+var ServeFlag = flag.String("serve", "", "serve the http api at an address, like :8080 for 127.0.0.1:8080, or 0.0.0.0:8080 for everyone")
+
+// cubeSession is a cube that clients share
+type cubeSession struct {
+	mu       sync.Mutex
+	Cube     *gocube.Cube
+	watchers map[chan gocube.Response]bool
+}
+
+// how many changes a watcher can fall behind before it is dropped
+const watcherBacklog = 64
+
+// changes are the ops that change the cube, that watchers are told about
+func changes(op string) bool {
+	return op == "execute" || op == "undo" || op == "reset"
+}
+
+// turnsOf is how many face turns a node makes at most, counting no further
+// than limit. A face with a repeat is one turn, and a commutator or a
+// conjugate runs its parts twice at most.
+func turnsOf(node gocube.Node, limit int) int {
+	if node.Arr == nil {
+		return 1
+	}
+	once := 0
+	for _, n := range node.Arr {
+		once += turnsOf(n, limit)
+		if once > limit {
+			return limit + 1
+		}
+	}
+	if node.Commutator {
+		once *= 2
+	}
+	repeat := node.Repeat
+	if repeat < 1 {
+		repeat = 1
+	}
+	if once > 0 && repeat > limit/once {
+		return limit + 1
+	}
+	return once * repeat
+}
+
+// tooLong says when an expression would make too many turns for the server
+func tooLong(expr string) *gocube.ResponseError {
+	node, err := gocube.NewCube().Parse(expr)
+	if err != nil {
+		// respond says what is wrong with it
+		return nil
+	}
+	if turnsOf(node, maxServeTurns) > maxServeTurns {
+		return &gocube.ResponseError{Kind: "request", Message: fmt.Sprintf("%s makes more than %d turns", expr, maxServeTurns)}
+	}
+	return nil
+}
+
+// do does a request with the session locked, and tells the watchers when
+// it changed the cube
+func (sess *cubeSession) do(req gocube.Request) gocube.Response {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if req.Op == "execute" {
+		if rerr := tooLong(req.Expr); rerr != nil {
+			resp := gocube.Response{ID: req.ID, Op: req.Op, Expr: req.Expr, Error: rerr}
+			return sess.Cube.WithState(resp, nil)
+		}
+	}
+	cube, resp := respond(sess.Cube, req)
+	sess.Cube = cube
+	if resp.OK && changes(req.Op) {
+		for ch := range sess.watchers {
+			select {
+			case ch <- resp:
+			default:
+				// too slow to keep up, so it would show the wrong cube
+				delete(sess.watchers, ch)
+				close(ch)
+			}
+		}
+	}
+	return resp
+}
+
+// watch gets the state now, and the changes after it until stop
+func (sess *cubeSession) watch() (gocube.Response, chan gocube.Response, func()) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	_, state := respond(sess.Cube, gocube.Request{Op: "state"})
+	ch := make(chan gocube.Response, watcherBacklog)
+	if sess.watchers == nil {
+		sess.watchers = make(map[chan gocube.Response]bool)
+	}
+	sess.watchers[ch] = true
+	stop := func() {
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		if sess.watchers[ch] {
+			delete(sess.watchers, ch)
+			close(ch)
+		}
+	}
+	return state, ch, stop
+}
+
+// close stops every watcher, when the session is deleted, so that their
+// websockets close instead of watching a cube that is gone
+func (sess *cubeSession) close() {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	for ch := range sess.watchers {
+		delete(sess.watchers, ch)
+		close(ch)
+	}
+}
+
+type Server struct {
+	mu       sync.Mutex
+	sessions map[string]*cubeSession
+	next     int
+}
+
+func NewServer() *Server {
+	return &Server{sessions: make(map[string]*cubeSession)}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError is a failed request, with an error like -json has
+func writeError(w http.ResponseWriter, status int, kind string, err error) {
+	writeJSON(w, status, gocube.Response{Error: &gocube.ResponseError{Kind: kind, Message: err.Error()}})
+}
+
+// the biggest request body that is read
+const maxRequestBytes = 1 << 16
+
+// maxServeTurns is the most face turns that one expression can make, so
+// that something like (fdrfdbl)999999999 can not hold a session forever
+const maxServeTurns = 100000
+
+// readRequest reads the json body of a request, which may be empty, or
+// writes a 415 when the body is not json, or a 400 when it can not be read
+func readRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.ContentLength != 0 {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, "request", fmt.Errorf("the body should be application/json, not %q", mediaType))
+			return false
+		}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBytes)
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "request", err)
+		return false
+	}
+	return true
+}
+
+// session finds the session in the path, or writes a 404
+func (s *Server) session(w http.ResponseWriter, r *http.Request) *cubeSession {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "request", fmt.Errorf("no session %s", id))
+		return nil
+	}
+	return sess
+}
+
+// svgStyle draws the svg of a session in the western scheme, or in the
+// colors of ?colors=japanese+letters, which takes the words that the colors
+// command does. It does not use the mask, labels or colors of the prompt
+// that started the server, so every client gets the same picture.
+func svgStyle(r *http.Request) (*gocube.Style, error) {
+	drawing := gocube.NewStyle()
+	if words := strings.Fields(r.URL.Query().Get("colors")); len(words) > 0 {
+		if err := drawing.UseColorWords(words); err != nil {
+			return nil, err
+		}
+	}
+	return drawing, nil
+}
+
+// Create makes a session, with a name or the next number
+func (s *Server) Create(name string) (string, *cubeSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name == "" {
+		for {
+			s.next++
+			name = strconv.Itoa(s.next)
+			if _, ok := s.sessions[name]; !ok {
+				break
+			}
+		}
+	} else if _, ok := s.sessions[name]; ok {
+		return name, nil, fmt.Errorf("session %s already exists", name)
+	}
+	if strings.ContainsAny(name, "/ ") {
+		return name, nil, fmt.Errorf("a session name can not have / or spaces")
+	}
+	sess := &cubeSession{Cube: gocube.NewCube()}
+	s.sessions[name] = sess
+	return name, sess, nil
+}
+
+// respond does a request like -json does, with the session locked
+func (s *Server) respond(w http.ResponseWriter, r *http.Request, req gocube.Request) {
+	sess := s.session(w, r)
+	if sess == nil {
+		return
+	}
+	resp := sess.do(req)
+	status := http.StatusOK
+	switch {
+	case resp.Error == nil:
+	case resp.Error.Kind == "undo":
+		status = http.StatusConflict
+	default:
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, resp)
+}
+
+// Equal executes every side of an equation like a == b on a new cube,
+// and says whether they all leave the same stickers
+func Equal(expr string) (bool, []gocube.Response, *gocube.ResponseError) {
+	sides := strings.Split(expr, "==")
+	if len(sides) < 2 {
+		return false, nil, &gocube.ResponseError{Kind: "request", Message: "an equation needs ==, like [fr]6 == ()"}
+	}
+	equal := true
+	out := make([]gocube.Response, 0, len(sides))
+	for _, side := range sides {
+		if rerr := tooLong(side); rerr != nil {
+			return false, out, rerr
+		}
+		_, resp := respond(gocube.NewCube(), gocube.Request{Op: "execute", Expr: strings.TrimSpace(side)})
+		if resp.Error != nil {
+			resp.Error.Message = fmt.Sprintf("%s: %s", strings.TrimSpace(side), resp.Error.Message)
+			return false, out, resp.Error
+		}
+		if len(out) > 0 && out[0].Facelets != resp.Facelets {
+			equal = false
+		}
+		out = append(out, resp)
+	}
+	return equal, out, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", serveWebPage)
+	mux.HandleFunc("POST /api/sessions", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Name string `json:"name"`
+		}
+		if !readRequest(w, r, &body) {
+			return
+		}
+		id, sess, err := s.Create(body.Name)
+		if err != nil {
+			writeError(w, http.StatusConflict, "request", err)
+			return
+		}
+		resp := sess.do(gocube.Request{Op: "state"})
+		resp.ID = json.RawMessage(strconv.Quote(id))
+		writeJSON(w, http.StatusCreated, resp)
+	})
+	mux.HandleFunc("GET /api/sessions", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		ids := make([]string, 0, len(s.sessions))
+		for id := range s.sessions {
+			ids = append(ids, id)
+		}
+		s.mu.Unlock()
+		sort.Strings(ids)
+		writeJSON(w, http.StatusOK, map[string][]string{"sessions": ids})
+	})
+	mux.HandleFunc("GET /api/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.respond(w, r, gocube.Request{Op: "state"})
+	})
+	mux.HandleFunc("DELETE /api/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		sess := s.session(w, r)
+		if sess == nil {
+			return
+		}
+		s.mu.Lock()
+		delete(s.sessions, r.PathValue("id"))
+		s.mu.Unlock()
+		sess.close()
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	})
+	mux.HandleFunc("POST /api/sessions/{id}/execute", func(w http.ResponseWriter, r *http.Request) {
+		var req gocube.Request
+		if !readRequest(w, r, &req) {
+			return
+		}
+		req.Op = "execute"
+		s.respond(w, r, req)
+	})
+	mux.HandleFunc("POST /api/sessions/{id}/undo", func(w http.ResponseWriter, r *http.Request) {
+		s.respond(w, r, gocube.Request{Op: "undo"})
+	})
+	mux.HandleFunc("POST /api/sessions/{id}/reset", func(w http.ResponseWriter, r *http.Request) {
+		s.respond(w, r, gocube.Request{Op: "reset"})
+	})
+	mux.HandleFunc("GET /api/sessions/{id}/svg", func(w http.ResponseWriter, r *http.Request) {
+		sess := s.session(w, r)
+		if sess == nil {
+			return
+		}
+		drawing, err := svgStyle(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "request", err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		caption := r.URL.Query().Get("caption")
+		if r.URL.Query().Get("view") == "iso" {
+			drawing.IsoSVG(w, []*gocube.Cube{sess.Cube}, caption)
+		} else {
+			drawing.DrawSVG(w, []*gocube.Cube{sess.Cube}, caption)
+		}
+	})
+	mux.HandleFunc("GET /api/sessions/{id}/ws", func(w http.ResponseWriter, r *http.Request) {
+		sess := s.session(w, r)
+		if sess == nil {
+			return
+		}
+		ws, err := AcceptWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		state, events, stop := sess.watch()
+		defer stop()
+		if err := ws.WriteJSON(state); err != nil {
+			return
+		}
+		go func() {
+			for resp := range events {
+				if err := ws.WriteJSON(resp); err != nil {
+					break
+				}
+			}
+			// stopped, or dropped for being slow
+			ws.conn.Close()
+		}()
+		for {
+			message, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req gocube.Request
+			if err := json.Unmarshal(message, &req); err != nil {
+				ws.WriteJSON(gocube.Response{Error: &gocube.ResponseError{Kind: "request", Message: err.Error()}})
+				continue
+			}
+			if resp := sess.do(req); !resp.OK || !changes(req.Op) {
+				ws.WriteJSON(resp)
+			}
+		}
+	})
+	mux.HandleFunc("POST /api/eval", func(w http.ResponseWriter, r *http.Request) {
+		var req gocube.Request
+		if !readRequest(w, r, &req) {
+			return
+		}
+		equal, sides, rerr := Equal(req.Expr)
+		if rerr != nil {
+			writeJSON(w, http.StatusBadRequest, gocube.Response{Error: rerr})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "equal": equal, "sides": sides})
+	})
+	return mux
+}
+
+// Serve runs the api until it fails
+func Serve(addr string) error {
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
+	}
+	fmt.Printf("serving the api and the web page at %s\n", addr)
+	return http.ListenAndServe(addr, NewServer().Handler())
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gocube "github.com/rfielding/rustCube/cube"
+)
+
+/*
+  Slots are named cubes. Commands work on the current slot, and the
+  commands that need two cubes (bridge, diff, s, export both) use the
+  slot that was current before it:
+
+    use c     make slot c current, creating it if it is new
+    copy a b  copy the stickers of slot a into slot b
+    drop c    remove slot c
+
+  There are slots a and b to start with, like there always were two cubes.
+*/
+
+// Slot is a named cube, and the last command that ran on it
+type Slot struct {
+	Name    string
+	Cube    *gocube.Cube
+	Cmd     string
+	Repeats int
+}
+
+type Slots struct {
+	List    []*Slot
+	Current string
+	Other   string
+}
+
+func NewSlots() *Slots {
+	return &Slots{
+		List:    []*Slot{{Name: "a", Cube: gocube.NewCube()}, {Name: "b", Cube: gocube.NewCube()}},
+		Current: "a",
+		Other:   "b",
+	}
+}
+
+// Get finds a slot by name, or nil
+func (slots *Slots) Get(name string) *Slot {
+	for _, s := range slots.List {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func (slots *Slots) Cube() *gocube.Cube {
+	return slots.Get(slots.Current).Cube
+}
+
+func (slots *Slots) OtherCube() *gocube.Cube {
+	return slots.Get(slots.Other).Cube
+}
+
+// SetCube replaces the cube in the current slot, like n does
+func (slots *Slots) SetCube(cube *gocube.Cube) {
+	slots.Get(slots.Current).Cube = cube
+}
+
+// Ran records the last command of the current slot
+func (slots *Slots) Ran(cmd string, repeats int) {
+	s := slots.Get(slots.Current)
+	s.Cmd, s.Repeats = cmd, repeats
+}
+
+// Use makes a slot current, and the current one the other
+func (slots *Slots) Use(name string) {
+	if name == slots.Current {
+		return
+	}
+	if slots.Get(name) == nil {
+		slots.List = append(slots.List, &Slot{Name: name, Cube: gocube.NewCube()})
+	}
+	slots.Current, slots.Other = name, slots.Current
+}
+
+// Swap is s: the other slot becomes current
+func (slots *Slots) Swap() {
+	slots.Current, slots.Other = slots.Other, slots.Current
+}
+
+// Copy copies stickers between slots, creating the destination if it is new.
+// The destination keeps its history, so the copy can be undone with p.
+func (slots *Slots) Copy(from, to string) error {
+	src := slots.Get(from)
+	if src == nil {
+		return fmt.Errorf("no slot %s. slots: %s", from, slots.Names())
+	}
+	dst := slots.Get(to)
+	if dst == nil {
+		dst = &Slot{Name: to, Cube: gocube.NewCube()}
+		slots.List = append(slots.List, dst)
+	}
+	if src == dst {
+		return nil
+	}
+	before := dst.Cube.State()
+	dst.Cube.History = append(dst.Cube.History, before)
+	dst.Cube.Before = &before
+	for k, v := range src.Cube.Stickers {
+		dst.Cube.Stickers[k] = v
+	}
+	dst.Cmd, dst.Repeats = "copy "+from, 1
+	return nil
+}
+
+// Drop removes a slot. The current and other slots can not be dropped.
+func (slots *Slots) Drop(name string) error {
+	if name == slots.Current || name == slots.Other {
+		return fmt.Errorf("slot %s is in use", name)
+	}
+	for i, s := range slots.List {
+		if s.Name == name {
+			slots.List = append(slots.List[:i], slots.List[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no slot %s. slots: %s", name, slots.Names())
+}
+
+// Names lists slots for help
+func (slots *Slots) Names() string {
+	names := make([]string, 0, len(slots.List))
+	for _, s := range slots.List {
+		names = append(names, s.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+// Cubes and Captions are what the renderers draw, in the order slots were made
+func (slots *Slots) Cubes() []*gocube.Cube {
+	cubes := make([]*gocube.Cube, 0, len(slots.List))
+	for _, s := range slots.List {
+		cubes = append(cubes, s.Cube)
+	}
+	return cubes
+}
+
+func (slots *Slots) Captions() []string {
+	captions := make([]string, 0, len(slots.List))
+	for _, s := range slots.List {
+		mark := " "
+		switch s.Name {
+		case slots.Current:
+			mark = "*"
+		case slots.Other:
+			mark = "+"
+		}
+		caption := fmt.Sprintf("%s%s", mark, s.Name)
+		if s.Cmd != "" {
+			caption += fmt.Sprintf(": %s x %d", s.Cmd, s.Repeats)
+		}
+		captions = append(captions, caption)
+	}
+	return captions
+}
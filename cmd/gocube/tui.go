@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	gocube "github.com/rfielding/rustCube/cube"
+)
+
+/*
+  tui is a full screen mode where a key turns a face, for exploring:
+
+    u r f d l b   turn a face
+    U R F D L B   turn the whole cube
+    /u or alt-u   turn it back
+    p             undo
+    n             new cube
+    :             type an expression, like [fr]3
+    q             back to the prompt
+
+  Next to the cube are the moves made in it, the period of those moves
+  (how many times they can be made before the cube is back to where it
+  started), and the undo stack.
+*/
+
+// tuiEntry is something that was done in the tui, that p can undo
+type tuiEntry struct {
+	Typed string
+	Moves string
+}
+
+// tuiPanes are the lines to the right of the cube
+func tuiPanes(cube *gocube.Cube, log []tuiEntry, height int) []string {
+	moves := make([]string, 0)
+	for _, e := range log {
+		moves = append(moves, strings.Fields(e.Moves)...)
+	}
+	lines := []string{fmt.Sprintf("moves: %d", len(moves))}
+	// wrap the moves, keeping the last of them
+	row := ""
+	rows := make([]string, 0)
+	for _, m := range moves {
+		if len(row)+len(m) > 36 {
+			rows = append(rows, row)
+			row = ""
+		}
+		row += m + " "
+	}
+	if row != "" {
+		rows = append(rows, row)
+	}
+	if len(rows) > 4 {
+		rows = append([]string{"..."}, rows[len(rows)-3:]...)
+	}
+	for _, r := range rows {
+		lines = append(lines, "  "+r)
+	}
+	lines = append(lines, "")
+
+	period := "-"
+	if len(moves) > 0 {
+		if node, err := cube.Parse(strings.Join(moves, " ")); err == nil {
+			if p, err := cube.PermOf(node); err == nil {
+				period = fmt.Sprintf("%d", p.Order())
+			}
+		}
+	}
+	lines = append(lines, fmt.Sprintf("period: %s", period))
+	if raw, _, _, err := gocube.MeasureMoves(strings.Join(moves, " ")); err == nil {
+		lines = append(lines, fmt.Sprintf("htm %d qtm %d", raw.HTM, raw.QTM))
+	}
+	lines = append(lines, "")
+
+	lines = append(lines, fmt.Sprintf("undo stack: %d", len(cube.History)))
+	shown := log
+	if len(shown) > 5 {
+		shown = shown[len(shown)-5:]
+	}
+	for i := len(shown) - 1; i >= 0; i-- {
+		lines = append(lines, fmt.Sprintf("  %3d %s", len(cube.History)-(len(shown)-1-i), shown[i].Typed))
+	}
+	if earlier := len(cube.History) - len(log); earlier > 0 {
+		lines = append(lines, fmt.Sprintf("  %d from before", earlier))
+	}
+	lines = append(lines, "")
+
+	lines = append(lines,
+		"u r f d l b  turn a face",
+		"U R F D L B  turn the cube",
+		"/u or alt-u  turn it back",
+		"p undo  n new  : expression  q quit",
+	)
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// TUI runs the full screen mode on the current cube, until q
+func TUI(slots *Slots, editor *Editor) error {
+	restore, err := keyMode()
+	if err != nil {
+		return fmt.Errorf("the tui needs a terminal")
+	}
+	defer restore()
+	fmt.Printf("\u001b[?1049h")
+	defer fmt.Printf("\u001b[?1049l")
+
+	log := make([]tuiEntry, 0)
+	message := ""
+	inverse := false
+	run := func(typed string) {
+		cube := slots.Cube()
+		node, err := cube.Parse(typed)
+		if err != nil {
+			message = fmt.Sprintf("parse error: %s", err)
+			return
+		}
+		moves, err := cube.ExecuteCommand(node)
+		if err != nil {
+			// put the cube back, so that the log and the undo stack stay in step
+			cube.Pop()
+			message = fmt.Sprintf("execute error: %s", err)
+			return
+		}
+		log = append(log, tuiEntry{Typed: typed, Moves: moves})
+		slots.Ran(typed, 1)
+	}
+	for {
+		cube := slots.Cube()
+		net, width, err := style.NetLines(cube)
+		if err != nil {
+			return err
+		}
+		panes := tuiPanes(cube, log, len(net))
+		fmt.Printf("\u001b[H\u001b[2J")
+		fmt.Printf("cube %s\n\n", slots.Current)
+		for i, p := range panes {
+			left := strings.Repeat(" ", width)
+			if i < len(net) && net[i] != "" {
+				left = net[i]
+			}
+			fmt.Printf("%s    %s\n", left, p)
+		}
+		if inverse {
+			message = "/"
+		}
+		fmt.Printf("\n%s", message)
+		message = ""
+
+		b, err := editor.In.ReadByte()
+		if err != nil {
+			return err
+		}
+		alt := false
+		if b == 27 {
+			// alt sends escape first. arrows and other keys are ignored.
+			next, err := editor.In.ReadByte()
+			if err != nil {
+				return err
+			}
+			if next == '[' || next == 'O' {
+				for next < 0x40 || next > 0x7e || next == '[' || next == 'O' {
+					if next, err = editor.In.ReadByte(); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			b, alt = next, true
+		}
+		key := string(b)
+		switch {
+		case strings.Contains("urfdlbURFDLB", key):
+			if inverse || alt {
+				key = "/" + key
+			}
+			inverse = false
+			run(key)
+		case key == "/":
+			inverse = !inverse
+		case key == "p" || b == 127:
+			if cube.Pop() {
+				if len(log) > 0 {
+					log = log[:len(log)-1]
+				}
+			} else {
+				message = "nothing to undo!"
+			}
+		case key == "n":
+			slots.SetCube(gocube.NewCube())
+			log = log[:0]
+		case key == ":":
+			fmt.Printf("\r\u001b[K")
+			line, err := editor.ReadLine(":")
+			if err == nil && line != "" {
+				run(line)
+			}
+		case key == "q" || b == 4:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	gocube "github.com/rfielding/rustCube/cube"
+)
+
+/*
+  -watch follows a session of -serve in the terminal, over its websocket:
+
+    gocube -serve :8080
+    gocube -watch ws://localhost:8080/api/sessions/demo/ws
+
+  The cube is drawn again every time anyone changes it. Lines typed into a
+  watcher are sent to the session: an expression executes, p undoes, n is a
+  new cube and q stops watching. Everyone watching sees them, in the order
+  the server got them.
+*/
+
+var WatchFlag = flag.String("watch", "", "watch a session of -serve, like ws://localhost:8080/api/sessions/demo/ws")
+
+// watchRequest is what a line typed into a watcher asks for
+func watchRequest(line string) gocube.Request {
+	switch line {
+	case "p":
+		return gocube.Request{Op: "undo"}
+	case "n":
+		return gocube.Request{Op: "reset"}
+	}
+	return gocube.Request{Op: "execute", Expr: line}
+}
+
+// Watch draws a session whenever it changes, until the server or q closes it
+func Watch(address string) error {
+	ws, err := DialWebSocket(address)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	go func() {
+		rdr := bufio.NewReader(os.Stdin)
+		for {
+			line, err := rdr.ReadString('\n')
+			line = strings.TrimSpace(line)
+			if line == "q" {
+				// the server says goodbye back, which ends ReadMessage
+				ws.SendClose()
+				return
+			}
+			if line != "" {
+				ws.WriteJSON(watchRequest(line))
+			}
+			if err != nil {
+				// no more to send, but keep watching
+				return
+			}
+		}
+	}()
+	var previous map[string]string
+	for {
+		message, err := ws.ReadMessage()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var resp gocube.Response
+		if err := json.Unmarshal(message, &resp); err != nil {
+			return err
+		}
+		cube := gocube.NewCube()
+		if resp.Error != nil {
+			printRed(fmt.Sprintf("%s error: %s", resp.Error.Kind, resp.Error.Message))
+			continue
+		}
+		cube.Stickers = resp.Stickers
+		if previous != nil {
+			// so that highlight shows what changed
+			before := gocube.StateOf(previous)
+			cube.Before = &before
+		}
+		previous = resp.Stickers
+		clearScreen()
+		Draw(strings.TrimSpace(resp.Op+" "+resp.Expr), 1, []*gocube.Cube{cube}, nil)
+		if len(resp.Moves) > 0 {
+			fmt.Printf("moves: %s\n", strings.Join(resp.Moves, " "))
+		}
+		fmt.Printf("solved: %t  undo stack: %d\n", resp.Solved, resp.Undo)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+/*
+  -serve also serves a web page at /, which does what the prompt does
+  without a terminal: type an expression, see how it parsed and the moves
+  it made, undo, and see the net or the 3d view. The page is built into
+  the binary, so there is nothing else to install.
+
+  Every page is a session named after the # in its address, so sending
+  the address to someone shows them the same cube.
+*/
+
+//go:embed web/index.html
+var webPage []byte
+
+func serveWebPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(webPage)
+}
@@ -0,0 +1,348 @@
+package cube
+
+import (
+	"bufio"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+  Colors come from a scheme, which gives every face an rgb color, and a
+  mode, which says what escapes the terminal understands:
+
+    16         the basic ansi colors. There is no orange, so magenta stands in.
+               Pictures and gifs always use the rgb colors, so they have orange.
+    256        the xterm color cube
+    truecolor  the rgb color as it is
+
+  For telling colors apart without seeing them, an overlay writes a letter
+  or a symbol on every sticker.
+
+  The colors file in the config dir sets these, one per line, and a face
+  color on its own line makes a custom scheme:
+
+    scheme: japanese
+    mode: truecolor
+    overlay: letters
+    b: #ff8000
+
+  A Style holds all of this, so that programs can draw cubes in colors of
+  their own without changing how anything else draws them.
+*/
+
+// ColorSchemes are the face colors of the presets
+var ColorSchemes = map[string]map[string]string{
+	"western": {
+		"u": "#ffffff",
+		"r": "#0046ad",
+		"f": "#b71234",
+		"d": "#ffd500",
+		"l": "#009b48",
+		"b": "#ff5800",
+	},
+	"japanese": {
+		"u": "#ffffff",
+		"r": "#ffd500",
+		"f": "#b71234",
+		"d": "#0046ad",
+		"l": "#009b48",
+		"b": "#ff5800",
+	},
+}
+
+var ColorModes = []string{"16", "256", "truecolor"}
+var ColorOverlays = []string{"none", "letters", "symbols"}
+
+// ColorConfig is what the colors file and the colors command set
+type ColorConfig struct {
+	Scheme  string
+	Mode    string
+	Overlay string
+}
+
+// Style is how cubes are drawn: with ansi escapes or letters, in which
+// colors, with which stickers highlighted, labeled or greyed out
+type Style struct {
+	Ansi   bool
+	Colors ColorConfig
+	// FaceRGB are the colors of the faces in the scheme that is used.
+	// Draw uses magenta for b when it only has the basic ansi colors.
+	FaceRGB map[string]string
+	// Custom is the scheme that faces in a colors file made, if any
+	Custom map[string]string
+	// Highlight is the style of changed stickers
+	Highlight string
+	// Labels is what Draw writes on stickers
+	Labels string
+	// Mask greys out the stickers that are not in it, when it is set
+	Mask *Mask
+}
+
+// NewStyle draws with ansi colors in the western scheme, and nothing else
+func NewStyle() *Style {
+	style := &Style{
+		Ansi:      true,
+		Colors:    ColorConfig{Scheme: "western", Mode: "16", Overlay: "none"},
+		FaceRGB:   make(map[string]string),
+		Highlight: "none",
+		Labels:    "none",
+	}
+	for f, rgb := range ColorSchemes["western"] {
+		style.FaceRGB[f] = rgb
+	}
+	return style
+}
+
+// the basic ansi colors, by the rgb colors they are nearest to
+var ansiBasic = []struct {
+	RGB  string
+	Code int
+}{
+	{"#ffffff", 7},
+	{"#0046ad", 4},
+	{"#b71234", 1},
+	{"#ffd500", 3},
+	{"#009b48", 2},
+	{"#ff5800", 5},
+	{"#00ffff", 6},
+	{"#000000", 0},
+}
+
+// overlay symbols, by the face that a color belongs to in the scheme
+var overlaySymbols = map[string]string{"u": "○", "r": "◆", "f": "■", "d": "●", "l": "▲", "b": "✚"}
+
+// rgbOf reads colors like #ff5800
+func rgbOf(s string) (int, int, int, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return 0, 0, 0, fmt.Errorf("not a color: %s", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("not a color: %s", s)
+	}
+	return int(v >> 16), int(v >> 8 & 0xff), int(v & 0xff), nil
+}
+
+// nearestBasic is the ansi color, 0 to 7, that is closest to an rgb color
+func nearestBasic(s string) int {
+	r, g, b, _ := rgbOf(s)
+	best, bestDist := 0, -1
+	for _, a := range ansiBasic {
+		ar, ag, ab, _ := rgbOf(a.RGB)
+		d := (r-ar)*(r-ar) + (g-ag)*(g-ag) + (b-ab)*(b-ab)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = a.Code, d
+		}
+	}
+	return best
+}
+
+// colorEscape is the sgr parameters for a color, as a background or not
+func (style *Style) colorEscape(rgb string, background bool) string {
+	base := 30
+	if background {
+		base = 40
+	}
+	switch style.Colors.Mode {
+	case "256":
+		r, g, b, _ := rgbOf(rgb)
+		n := 16 + 36*((r*5+127)/255) + 6*((g*5+127)/255) + (b*5+127)/255
+		return fmt.Sprintf("%d;5;%d", base+8, n)
+	case "truecolor":
+		r, g, b, _ := rgbOf(rgb)
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, r, g, b)
+	}
+	return strconv.Itoa(base + nearestBasic(rgb))
+}
+
+// textOn is a text color that can be read on a background
+func (style *Style) textOn(rgb string) string {
+	if style.Colors.Mode == "16" {
+		// like it always was: white on magenta, black on the rest
+		if nearestBasic(rgb) == 5 {
+			return "37"
+		}
+		return "30"
+	}
+	r, g, b, _ := rgbOf(rgb)
+	if 299*r+587*g+114*b < 128000 {
+		return "37"
+	}
+	return "30"
+}
+
+// stickerRGB is the color of a sticker value, which may be masked
+func (style *Style) stickerRGB(v string) string {
+	if isMasked(v) {
+		return MaskedRGB
+	}
+	return style.FaceRGB[v]
+}
+
+// stickerText is a sticker value as a letter, with - for masked
+func stickerText(v string) string {
+	if isMasked(v) {
+		return maskedSuffix
+	}
+	return v
+}
+
+// overlayOf is what is written on a sticker of color v, one character wide
+func (style *Style) overlayOf(v string) string {
+	if isMasked(v) {
+		return " "
+	}
+	switch style.Colors.Overlay {
+	case "letters":
+		return v
+	case "symbols":
+		return overlaySymbols[v]
+	}
+	return " "
+}
+
+// StickerBlock draws a sticker of color v, two characters wide
+func (style *Style) StickerBlock(v string) string {
+	if !style.Ansi {
+		return stickerText(v) + " "
+	}
+	rgb := style.stickerRGB(v)
+	return fmt.Sprintf("\u001b[1;%s;%sm%s \u001b[0m", style.colorEscape(rgb, true), style.textOn(rgb), style.overlayOf(v))
+}
+
+// FaceColored writes s in the color of face v
+func (style *Style) FaceColored(v string, s string) string {
+	if !style.Ansi {
+		return s
+	}
+	return fmt.Sprintf("\u001b[1;%sm%s\u001b[0m", style.colorEscape(style.stickerRGB(v), false), s)
+}
+
+// scheme is a preset scheme, or the custom one
+func (style *Style) scheme(name string) map[string]string {
+	if name == "custom" && style.Custom != nil {
+		return style.Custom
+	}
+	return ColorSchemes[name]
+}
+
+// SetColors checks a config, and uses it. Faces in custom change the
+// colors of the scheme, and make it the custom scheme.
+func (style *Style) SetColors(config ColorConfig, custom map[string]string) error {
+	scheme := style.scheme(config.Scheme)
+	if scheme == nil {
+		return fmt.Errorf("no scheme %s. schemes: %s", config.Scheme, style.SchemeNames())
+	}
+	if !slices.Contains(ColorModes, config.Mode) {
+		return fmt.Errorf("no mode %s. modes: %s", config.Mode, strings.Join(ColorModes, " "))
+	}
+	if !slices.Contains(ColorOverlays, config.Overlay) {
+		return fmt.Errorf("no overlay %s. overlays: %s", config.Overlay, strings.Join(ColorOverlays, " "))
+	}
+	faces := make(map[string]string)
+	for f, rgb := range scheme {
+		faces[f] = rgb
+	}
+	for f, rgb := range custom {
+		if _, ok := faces[f]; !ok {
+			return fmt.Errorf("%s is not a face", f)
+		}
+		if _, _, _, err := rgbOf(rgb); err != nil {
+			return err
+		}
+		faces[f] = rgb
+	}
+	if len(custom) > 0 {
+		style.Custom = faces
+		config.Scheme = "custom"
+	}
+	for f, rgb := range faces {
+		style.FaceRGB[f] = rgb
+	}
+	style.Colors = config
+	return nil
+}
+
+// SchemeNames lists schemes for help
+func (style *Style) SchemeNames() string {
+	names := make([]string, 0, len(ColorSchemes)+1)
+	for name := range ColorSchemes {
+		names = append(names, name)
+	}
+	if style.Custom != nil {
+		names = append(names, "custom")
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+// ParseColors reads a colors file, starting from the config of the style
+func (style *Style) ParseColors(text string) (ColorConfig, map[string]string, error) {
+	config := style.Colors
+	custom := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	line := 0
+	for scanner.Scan() {
+		line++
+		s := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(s, "--"); i >= 0 {
+			s = strings.TrimSpace(s[:i])
+		}
+		if s == "" {
+			continue
+		}
+		name, value, found := strings.Cut(s, ":")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if !found || value == "" {
+			return config, custom, fmt.Errorf("line %d: should look like name: value", line)
+		}
+		switch name {
+		case "scheme":
+			config.Scheme = value
+			if value == "custom" {
+				// the faces that follow say what it is
+				config.Scheme = "western"
+			}
+		case "mode":
+			config.Mode = value
+		case "overlay":
+			config.Overlay = value
+		default:
+			custom[name] = value
+		}
+	}
+	return config, custom, nil
+}
+
+// UseColorWords sets the scheme, mode and overlay from words in any order
+func (style *Style) UseColorWords(words []string) error {
+	config := style.Colors
+	for _, w := range words {
+		switch {
+		case style.scheme(w) != nil:
+			config.Scheme = w
+		case slices.Contains(ColorModes, w):
+			config.Mode = w
+		case slices.Contains(ColorOverlays, w):
+			config.Overlay = w
+		default:
+			return fmt.Errorf("%s is not a scheme, mode or overlay", w)
+		}
+	}
+	return style.SetColors(config, nil)
+}
+
+// ColorsText is the config as the colors file writes it
+func (style *Style) ColorsText() string {
+	text := fmt.Sprintf("scheme: %s\nmode: %s\noverlay: %s\n", style.Colors.Scheme, style.Colors.Mode, style.Colors.Overlay)
+	if style.Colors.Scheme == "custom" {
+		for _, f := range []string{"u", "r", "f", "d", "l", "b"} {
+			text += fmt.Sprintf("%s: %s\n", f, style.FaceRGB[f])
+		}
+	}
+	return text
+}
@@ -1,15 +1,14 @@
-package main
+// Package cube is the model of a 3x3x3 cube, and the move language that
+// turns it: Parse an expression into a Node, and Execute it on a Cube.
+// Rendering writes to an io.Writer with a Style, and failures are errors.
+// The only panic is when the package loads, if its own tables are wrong.
+package cube
 
 import (
-	"bufio"
-	"flag"
 	"fmt"
-	"os"
 	"strings"
 )
 
-var PostTest = flag.Bool("postTest", false, "post test on start")
-
 /*
   This is a Go implementation just so that I can get it done.
   It is very unproductive to use Rust for writing a parser.
@@ -28,7 +27,18 @@ type Cube struct {
 	Faces      []string
 	Opposite   map[string]string
 	Stickers   map[string]string
-	History    []map[string]string
+	// History is the state before every command, for Pop
+	History []State
+	// Before is the state before the last command, which highlight compares
+	// with. Pop clears it, since the command it was for is gone.
+	Before *State
+	// Trace is called before every face turn that Execute makes, with the
+	// path to the node that made it, as indexes into Arr
+	Trace     func(path []int, face string, turn int)
+	tracePath []int
+	// Visit is called as Execute leaves every node, with what it did there
+	Visit  func(visit Visit)
+	visits int
 }
 
 type Node struct {
@@ -41,8 +51,6 @@ type Node struct {
 	Reflection string
 }
 
-var UseAnsi = true
-
 // tests happen in an order that finds most primitive bugs that should cause
 // later cases to fail, and teaches user how to think about the algebra
 // when looking at examples
@@ -74,148 +82,18 @@ var EqTest = [][]string{
 	{"(x {l /d} [/d /f] )   -- mirror image a move accros axis R. negate all faces and swap f-b,l-r,u~d. to reuse moves.", "{/rd}[df]"},
 }
 
-func strippedComment(s string) string {
+func StrippedComment(s string) string {
 	return strings.Trim(strings.Split(s, "-")[0], " ")
 }
 
 func sameMeaning(s string) string {
 	// strip spaces after stripped comments
-	s = strippedComment(s)
+	s = StrippedComment(s)
 	// strip spaces
 	s = strings.Replace(s, " ", "", -1)
 	return s
 }
 
-func (cube *Cube) assert(s string) {
-	if *PostTest {
-		panic(s)
-	} else {
-		cube.PrintRed(s)
-	}
-}
-
-/*
-It seems a little strange to do this instead of standard Go test, but
-I will include integration tests if I am to provide internal parameters,
-for example to hook up to OpenAI and ask it to solve cubes.
-
-But any parameters not compiled in would be cause to do a PostTest.
-*/
-func (cube *Cube) PostTest() {
-	fmt.Printf("running post test\n")
-	checkInterpretation := func(s string, theCube *Cube) Node {
-		fmt.Printf("checkInterpretation: %s\n", s)
-		expect := "(" + s + ")"
-		parsed, err := theCube.Parse(s)
-		if err != nil {
-			cube.assert(fmt.Sprintf("parse error on example %s: %s\n", s, err))
-		}
-		got := parsed.Print()
-		got = sameMeaning(got)
-		expect = sameMeaning(expect)
-		if expect != got {
-			cube.assert(fmt.Sprintf("expect interpretation of %s to be: %s\n", expect, got))
-		}
-		return parsed
-	}
-
-	checkExecution := func(parsed Node, theCube *Cube) {
-		fmt.Printf("checkExecution: %s\n", parsed.Print())
-		execution, err := theCube.ExecuteCommand(parsed)
-		if err != nil {
-			cube.assert(fmt.Sprintf("execute error on example %s: %s\n", parsed.Print(), err))
-		}
-		_ = execution
-	}
-
-	checkInvertability := func(s string) {
-		fmt.Printf("checkInvertability: %s\n", s)
-		if len(s) < 3 {
-			return
-		}
-		sNot := s
-		if s[0] == '/' && (s[1] == '(' || s[1] == '[') {
-			sNot = s[1:]
-		} else {
-			sNot = "/(" + s + ")"
-		}
-		c1 := NewCube()
-		node, err := c1.Parse(s)
-		if err != nil {
-			cube.assert(fmt.Sprintf("parse error on example invertability chech %s: %s\n", s, err))
-		}
-		ex1, err := c1.ExecuteCommand(node)
-		if err != nil {
-			cube.assert(fmt.Sprintf("execute error on example invertability chech %s: %s\n", s, err))
-		}
-		node, err = c1.Parse(sNot)
-		if err != nil {
-			cube.assert(fmt.Sprintf("parse error on example invertability chech %s: %s\n", sNot, err))
-		}
-		ex2, err := c1.ExecuteCommand(node)
-		if err != nil {
-			cube.assert(fmt.Sprintf("execute error on example invertability chech %s: %s\n", sNot, err))
-		}
-		for k, v := range c1.Stickers {
-			if string(k[0]) != v {
-				cube.assert(
-					fmt.Sprintf(
-						"inverse check: %s not inverted by %s.\nfwd: %s\nrev: %s\n",
-						s,
-						sNot,
-						ex1,
-						ex2,
-					),
-				)
-			}
-		}
-	}
-
-	for i := range EqTest {
-		// check the INTERPRETATION after a parse
-		s := strippedComment(EqTest[i][0])
-		checkInvertability(s)
-
-		cube1 := NewCube()
-		parsed := checkInterpretation(s, cube1)
-		checkExecution(parsed, cube1)
-
-		// compare next string cubes to current cube state.
-		// stickers should be the same to pass the test.
-		for j := 1; j < len(EqTest[i]); j++ {
-			s2 := strippedComment(EqTest[i][j])
-			checkInvertability(s2)
-
-			cube2 := NewCube()
-			parsed2 := checkInterpretation(s2, cube2)
-			checkExecution(parsed2, cube2)
-
-			// compare stickers to make sure they are equivalent as a parse
-			to := s2
-			if to == "" {
-				to = "()"
-			}
-			fmt.Printf("checkEquality: %s == %s\n", s, to)
-			for k := range cube1.Stickers {
-				got := cube1.Stickers[k]
-				expected := cube2.Stickers[k]
-				if got != expected {
-					cube.assert(
-						fmt.Sprintf(
-							"stickers should be the same in %s: sticker %s got %s instead of %s\n",
-							s,
-							k,
-							got,
-							expected,
-						),
-					)
-				}
-			}
-		}
-	}
-	fmt.Printf("post test complete\n\n")
-}
-
 func NewCube() *Cube {
 	cube := &Cube{
 		FaceCount:  6,
@@ -262,171 +140,61 @@ func NewCube() *Cube {
 			cube.Stickers[i+j] = i
 		}
 	}
-	// make sure that it satisfied solved cube invariants
+	return cube
+}
+
+// checkSolved makes sure that a new cube satisfies solved cube invariants
+func checkSolved(cube *Cube) error {
 	for s, v := range cube.Stickers {
 		if v[0] != s[0] {
-			panic(fmt.Sprintf("stickers should start with face name: %s vs %s", s, v))
+			return fmt.Errorf("stickers should start with face name: %s vs %s", s, v)
 		}
 	}
 	if cube.Stickers["bul"] != "" {
 		if cube.Stickers["blu"] == "b" {
-			panic("stickers should be clockwise, so bul should be blu")
+			return fmt.Errorf("stickers should be clockwise, so bul should be blu")
 		}
 	}
-	return cube
+	if len(StickerKeys) != StickerCount {
+		return fmt.Errorf("expected %d stickers, got %d", StickerCount, len(StickerKeys))
+	}
+	return nil
 }
 
-// Draw with an allowance for 2 cubes side-by-side
-func Draw(cmd string, repeats int, cubes []*Cube) {
-	fullMask := "%s  %s%s%s  %s%s%s  %s%s%s  %s"
-	edgeMask := "            %s%s%s            "
-
-	s := func(cube *Cube, x string) string {
-		v := cube.Stickers[x]
-		if v == "" {
-			panic(fmt.Sprintf("sticker is not mapped: %s\n%v", x, cube.Stickers))
-		}
-		// fg colors: 30 black, 31 red, 32 green, 33 yellow, 34 blue, 35 magenta, 36 cyan, 37 white
-		// bg colors: 40 black, 41 red, 42 green, 43 yellow, 44 blue, 45 magenta, 46 cyan, 47 white
-		if UseAnsi {
-			switch v {
-			case "u":
-				v = fmt.Sprintf("\u001b[1;47;30m  \u001b[0m")
-			case "r":
-				v = fmt.Sprintf("\u001b[1;44;30m  \u001b[0m")
-			case "f":
-				v = fmt.Sprintf("\u001b[1;41;30m  \u001b[0m")
-			case "d":
-				v = fmt.Sprintf("\u001b[1;43;30m  \u001b[0m")
-			case "l":
-				v = fmt.Sprintf("\u001b[1;42;30m  \u001b[0m")
-			case "b":
-				v = fmt.Sprintf("\u001b[1;45;37m  \u001b[0m")
-			}
-		} else {
-			switch v {
-			case "u":
-				v = "u "
-			case "r":
-				v = "r "
-			case "f":
-				v = "f "
-			case "d":
-				v = "d "
-			case "l":
-				v = "l "
-			case "b":
-				v = "b "
-			}
-		}
-		return v
+// The tables are checked, and the moves made from them, once when the
+// package loads. A failure here is a mistake in the tables, that no
+// caller can do anything about, so it is the one place that panics.
+func init() {
+	if err := checkSolved(NewCube()); err != nil {
+		panic(fmt.Sprintf("new cube: %s", err))
 	}
-
-	draw := func(mask string, args ...string) {
-		if mask == "" {
-			fmt.Println()
-			return
-		}
-		for c := 0; c < len(cubes); c++ {
-			a := make([]any, 0)
-			if mask == edgeMask {
-				for i := 0; i < 3; i++ {
-					a = append(a, s(cubes[c], args[i]))
-				}
-				fmt.Printf(edgeMask, a...)
-				fmt.Printf("      ")
-			}
-			if mask == fullMask {
-				for i := 0; i < 11; i++ {
-					a = append(a, s(cubes[c], args[i]))
-				}
-				fmt.Printf(fullMask, a...)
-				fmt.Printf("      ")
-			}
-		}
-		fmt.Println()
+	if err := makeFaceMoves(); err != nil {
+		panic(fmt.Sprintf("face moves: %s", err))
+	}
+	if err := makeRotations(); err != nil {
+		panic(fmt.Sprintf("rotations: %s", err))
+	}
+	if err := makeOppositeTurns(); err != nil {
+		panic(fmt.Sprintf("opposite view: %s", err))
 	}
-
-	draw(edgeMask,
-		"bld", "bd", "bdr",
-	)
-	draw(edgeMask,
-		"bl", "b", "br",
-	)
-	draw(edgeMask,
-		"bul", "bu", "bru",
-	)
-	draw("")
-	draw(edgeMask,
-		"ulb", "ub", "ubr",
-	)
-	draw(edgeMask,
-		"ul", "u", "ur",
-	)
-	draw(edgeMask,
-		"ufl", "uf", "urf",
-	)
-	draw("")
-	draw(fullMask,
-		"bul",
-		"lbu", "lu", "luf",
-		"flu", "fu", "fur",
-		"rfu", "ru", "rub",
-		"bru",
-	)
-	draw(fullMask,
-		"bl",
-		"lb", "l", "lf",
-		"fl", "f", "fr",
-		"rf", "r", "rb",
-		"br",
-	)
-	draw(fullMask,
-		"bld",
-		"ldb", "ld", "lfd",
-		"fdl", "fd", "frd",
-		"rdf", "rd", "rbd",
-		"bdr",
-	)
-	draw("")
-	draw(edgeMask,
-		"dlf", "df", "dfr",
-	)
-	draw(edgeMask,
-		"dl", "d", "dr",
-	)
-	draw(edgeMask,
-		"dbl", "db", "drb",
-	)
-	draw("")
-	draw(edgeMask,
-		"bld", "bd", "bdr",
-	)
-
-	draw("")
-
-	fmt.Printf("cmd: %s x %d\n", cmd, repeats)
-
 }
 
 // 1 turn of  and maybe center at face i,
 //
 //	physical parts: ru~ur, rub~ubr~bru
-func (cube *Cube) Turn1(f string, center bool) {
-	s := func(s string) string {
-		v := cube.Stickers[s]
-		if v == "" {
-			panic(fmt.Sprintf("sticker is not mapped: %s\n%v", s, cube.Stickers))
-		}
-		return v
+func (cube *Cube) Turn1(f string, center bool) error {
+	if len(cube.Adj[f]) != cube.FacePeriod {
+		return fmt.Errorf("not a face: %s", f)
 	}
-
+	var unmapped error
 	swap := func(a string, b string) {
-		// use s(a) to verify that value is not null
-		tmp := s(a)
-		cube.Stickers[a] = s(b)
-		cube.Stickers[b] = tmp
-		//fmt.Printf("%s %s\n", a, b)
+		// a sticker without a value is a mapping bug
+		for _, k := range []string{a, b} {
+			if cube.Stickers[k] == "" && unmapped == nil {
+				unmapped = fmt.Errorf("sticker is not mapped: %s", k)
+			}
+		}
+		cube.Stickers[a], cube.Stickers[b] = cube.Stickers[b], cube.Stickers[a]
 	}
 
 	// faces have a period of 4, move their stickers
@@ -474,46 +242,58 @@ func (cube *Cube) Turn1(f string, center bool) {
 			swap(e1a, e1b)
 		}
 	}
+	if unmapped != nil {
+		return unmapped
+	}
 	// make sure that there are still 9 stickers of every color!
 	counts := make(map[byte]int)
-	for _, v := range cube.Stickers {
+	for k, v := range cube.Stickers {
+		if v == "" {
+			return fmt.Errorf("sticker is not mapped: %s", k)
+		}
 		color := v[0]
 		counts[color]++
 	}
 	for i := 0; i < cube.FaceCount; i++ {
 		if counts[cube.Faces[i][0]] != 9 {
-			panic(fmt.Sprintf("face %s has %d stickers", cube.Faces[i], counts[cube.Faces[i][0]]))
+			return fmt.Errorf("face %s has %d stickers", cube.Faces[i], counts[cube.Faces[i][0]])
 		}
 	}
+	return nil
 }
 
 // turn a face *count* times, all cube or just a face
-func (cube *Cube) Turn(i string, count int) {
+func (cube *Cube) Turn(i string, count int) error {
 	//
 	all := cube.shouldTurnWholeCube(i)
 	i = strings.ToLower(i)
 
-	for count < 0 {
-		count += cube.FacePeriod
-	}
-	count = count % cube.FacePeriod
+	// big negative counts are as cheap as small ones
+	count = (count%cube.FacePeriod + cube.FacePeriod) % cube.FacePeriod
 
 	// turn a face count times.
 	if all {
 		// turn face and center
 		for n := 0; n < count; n++ {
-			cube.Turn1(i, true)
+			if err := cube.Turn1(i, true); err != nil {
+				return err
+			}
 		}
 		// triple is negative, needed to turn back face
 		ncount := ((cube.FacePeriod - 1) * count) % cube.FacePeriod
 		for n := 0; n < ncount; n++ {
-			cube.Turn1(cube.Opposite[i], false)
+			if err := cube.Turn1(cube.Opposite[i], false); err != nil {
+				return err
+			}
 		}
 	} else {
 		for n := 0; n < count; n++ {
-			cube.Turn1(i, false)
+			if err := cube.Turn1(i, false); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
 }
 
 func (cube *Cube) shouldTurnWholeCube(f string) bool {
@@ -530,90 +310,15 @@ func (cube *Cube) shouldTurnCube(f string) bool {
 	return false
 }
 
-func (cube *Cube) facesString(upperCase bool) string {
-	if !UseAnsi {
-		return " U  R  F  D  L  B"
-	}
-	ansiColors := map[string]string{
-		"u": "\u001b[1;37m%s\u001b[0m",
-		"r": "\u001b[1;34m%s\u001b[0m",
-		"f": "\u001b[1;31m%s\u001b[0m",
-		"d": "\u001b[1;33m%s\u001b[0m",
-		"l": "\u001b[1;32m%s\u001b[0m",
-		"b": "\u001b[1;35m%s\u001b[0m",
-	}
-
-	uc := func(s string) string {
-		if upperCase {
-			return strings.ToUpper(s)
-		}
-		return s
-	}
-
-	// use the middle piece location to find the color
-	// for u r f d l b
-	return fmt.Sprintf(" %s  %s  %s  %s  %s  %s",
-		fmt.Sprintf(ansiColors[cube.Stickers["u"]], uc("u")),
-		fmt.Sprintf(ansiColors[cube.Stickers["r"]], uc("r")),
-		fmt.Sprintf(ansiColors[cube.Stickers["f"]], uc("f")),
-		fmt.Sprintf(ansiColors[cube.Stickers["d"]], uc("d")),
-		fmt.Sprintf(ansiColors[cube.Stickers["l"]], uc("l")),
-		fmt.Sprintf(ansiColors[cube.Stickers["b"]], uc("b")),
-	)
-}
-
-func (cube *Cube) colorStr(color int, s string) string {
-	if !UseAnsi {
-		return s
-	}
-	return fmt.Sprintf("\u001b[1;%dm%s\u001b[0m", color, s)
+func (node Node) Print() string {
+	return node.PrintMarked(nil, nil)
 }
 
-func (cube *Cube) Help() {
-	cube.PrintRed("-----BEGIN HELP-----\n")
-	fmt.Printf("run inside rlwrap for better keyboard handling! ctrl-r to search commands.\n")
-	fmt.Printf("from gocube.go file at: %s\n", cube.colorStr(32, "https://github.com/rfielding/rustCube"))
-	fmt.Printf("conventions: Up Right Front Down Left Back\n")
-	fmt.Printf("reverse a turn with '/', like: /u\n")
-	fmt.Printf("commutators have period 6: ((ru)/(ur))6 => ()\n")
-	fmt.Printf("negate: /(rf) = /f /r\n")
-	fmt.Printf("commutator: [fr] => f r /f /r\n")
-	fmt.Printf("conjugate: {ru} => r u /r\n")
-	fmt.Printf("mirror across an axis: negate all faces and swap names on axis. ex: x[[fr]3u]=[[/f/l]3/u] \n")
-	fmt.Println()
-	for i := range EqTest {
-		for j := 0; j < len(EqTest[i]); j++ {
-			if j == 0 {
-				fmt.Printf("%s ", cube.colorStr(34, "example:"))
-			} else {
-				fmt.Printf(" == ")
-			}
-			v := EqTest[i][j]
-			if v == "" {
-				v = "()"
-			}
-			fmt.Printf("%s", v)
-		}
-		fmt.Println()
+// PrintMarked is Print, with the node at path written through mark
+func (node Node) PrintMarked(path []int, mark func(string) string) string {
+	if mark != nil && len(path) == 0 {
+		return mark(node.Print())
 	}
-	fmt.Printf("%s nru         -- start from new cube, then ru\n", cube.colorStr(34, "example:"))
-	fmt.Printf("%s n(fdrfdbl)5 -- for a deterministic scramble, you can find in rlwrap history\n", cube.colorStr(34, "example:"))
-	fmt.Println()
-	fmt.Printf("help: ? or h\n")
-	fmt.Printf("new cube: n\n")
-	fmt.Printf("toggle ansi colors: a\n")
-	fmt.Printf("test: run tests on expressions\n")
-	fmt.Printf("quit: q\n")
-	fmt.Println()
-	fmt.Printf("turn a face: %s\n", cube.facesString(false))
-	fmt.Printf("turn cube:   %s\n", cube.facesString(true))
-	fmt.Printf("pop move off history (undo): p\n")
-	fmt.Printf("swap cubes: s\n")
-	fmt.Printf("startup test flag: -postTest\n")
-	cube.PrintRed("-----END HELP-----\n")
-}
-
-func (node Node) Print() string {
 	v := ""
 	if node.Negate {
 		v += "/"
@@ -636,7 +341,11 @@ func (node Node) Print() string {
 			if i > 0 {
 				v += " "
 			}
-			v += n.Print()
+			if mark != nil && path[0] == i {
+				v += n.PrintMarked(path[1:], mark)
+			} else {
+				v += n.Print()
+			}
 		}
 		if node.Commutator {
 			if node.Conjugated {
@@ -788,7 +497,7 @@ func (cube *Cube) Parse(input string) (Node, error) {
 			// look ahead to complete the number, and look back to write the repeat
 			num := 0
 			numStop := i
-			for numStop < len(input) && '0' < input[numStop] && input[numStop] <= '9' {
+			for numStop < len(input) && '0' <= input[numStop] && input[numStop] <= '9' {
 				num = 10*num + int(input[numStop]-'0')
 				numStop++
 			}
@@ -819,21 +528,29 @@ func (cube *Cube) Pop() bool {
 	if len(cube.History) == 0 {
 		return false
 	}
-	// write stickers from history over current stickers
-	for k, v := range cube.History[len(cube.History)-1] {
-		cube.Stickers[k] = v
-	}
+	// put the stickers back the way they were
+	cube.SetState(cube.History[len(cube.History)-1])
 	// remove the last history
 	cube.History = cube.History[:len(cube.History)-1]
+	cube.Before = nil
 	return true
 }
 
-func (cube *Cube) ExecuteCommand(node Node) (string, error) {
-	// append a copy of the stickers before this execution
-	cube.History = append(cube.History, make(map[string]string))
-	for k, v := range cube.Stickers {
-		cube.History[len(cube.History)-1][k] = v
+// Apply executes moves that a search found, so that they can be undone with Pop
+func (cube *Cube) Apply(moves string) error {
+	nodes, err := cube.Parse(moves)
+	if err != nil {
+		return err
 	}
+	_, err = cube.ExecuteCommand(nodes)
+	return err
+}
+
+func (cube *Cube) ExecuteCommand(node Node) (string, error) {
+	// remember the state before this execution
+	before := cube.State()
+	cube.History = append(cube.History, before)
+	cube.Before = &before
 	return cube.Execute(node, 0, 0, 0, 0, 0)
 }
 
@@ -848,32 +565,52 @@ func (cube *Cube) Execute(node Node, negates, xflips, yflips, zflips, wflips int
 	if node.Negate {
 		negates++
 	}
+	if node.Arr != nil && node.Reflection != "" {
+		switch node.Reflection {
+		case "x":
+			xflips++
+		case "y":
+			yflips++
+		case "z":
+			zflips++
+		case "w":
+			wflips++
+		}
+	}
+	if cube.Visit != nil {
+		visit := Visit{
+			Seq:     cube.visits,
+			Path:    append([]int{}, cube.tracePath...),
+			Node:    node,
+			Negates: negates,
+			Flips:   [4]int{xflips, yflips, zflips, wflips},
+		}
+		cube.visits++
+		defer func() {
+			visit.Moves = strings.TrimSpace(outcome)
+			cube.Visit(visit)
+		}()
+	}
 	if node.Arr != nil {
-		if node.Reflection != "" {
-			switch node.Reflection {
-			case "x":
-				xflips++
-			case "y":
-				yflips++
-			case "z":
-				zflips++
-			case "w":
-				wflips++
-			}
-		}
-		fwd := make([]Node, 0)
+		// fwd are indexes into Arr, so that Trace can say which node runs
+		fwd := make([]int, 0)
 		for i := 0; i < len(node.Arr); i++ {
-			n := node.Arr[i]
+			n := i
 			if negates%2 == 1 {
-				n = node.Arr[len(node.Arr)-1-i]
+				n = len(node.Arr) - 1 - i
 			}
 			fwd = append(fwd, n)
 		}
+		run := func(n int, negates int) (string, error) {
+			cube.tracePath = append(cube.tracePath, n)
+			defer func() { cube.tracePath = cube.tracePath[:len(cube.tracePath)-1] }()
+			return cube.Execute(node.Arr[n], negates, xflips, yflips, zflips, wflips)
+		}
 		// interpret as repeats bind latest
 		for i := 0; i < repeat; i++ {
 			if !node.Commutator || (node.Commutator && negates%2 == 0) {
 				for _, cmd := range fwd {
-					result, err := cube.Execute(cmd, negates, xflips, yflips, zflips, wflips)
+					result, err := run(cmd, negates)
 					if err != nil {
 						return outcome, fmt.Errorf("error in %s at %s: %s", outcome, result, err)
 					}
@@ -884,7 +621,7 @@ func (cube *Cube) Execute(node Node, negates, xflips, yflips, zflips, wflips int
 				for i := 0; i < len(fwd); i++ {
 					cmd := fwd[i]
 					if !node.Conjugated || (i == 0 && negates%2 == 0) || (i != 0 && negates%2 == 1) {
-						result, err := cube.Execute(cmd, negates+1, xflips, yflips, zflips, wflips)
+						result, err := run(cmd, negates+1)
 						if err != nil {
 							return outcome, fmt.Errorf("error in %s at %s: %s", outcome, result, err)
 						}
@@ -893,7 +630,7 @@ func (cube *Cube) Execute(node Node, negates, xflips, yflips, zflips, wflips int
 				}
 				if negates%2 == 1 {
 					for _, cmd := range fwd {
-						result, err := cube.Execute(cmd, negates, xflips, yflips, zflips, wflips)
+						result, err := run(cmd, negates)
 						if err != nil {
 							return outcome, fmt.Errorf("error in %s at %s: %s", outcome, result, err)
 						}
@@ -952,128 +689,13 @@ func (cube *Cube) Execute(node Node, negates, xflips, yflips, zflips, wflips int
 			} else {
 				outcome += fmt.Sprintf("/%s%s ", f, rstr)
 			}
-			cube.Turn(f, turn)
-		}
-	}
-	return outcome, nil
-}
-
-// print a message in red if ansi
-func (cube *Cube) PrintRed(msg string) {
-	if UseAnsi {
-		fmt.Printf("\u001b[1;31m%s\u001b[0m\n", msg)
-	} else {
-		fmt.Printf("%s\n", msg)
-	}
-}
-
-func Loop() {
-	cube := NewCube()
-	cube2 := NewCube()
-
-	// loop to get and anlyze a line and draw the screen
-	cmd := ""
-	repeats := 0
-	prevCmd := ""
-	cube.Help()
-
-	rdr := bufio.NewReader(os.Stdin)
-	for {
-		Draw(cmd, repeats, []*Cube{cube, cube2})
-
-		fmt.Printf("\u25B6 ")
-		var err error
-		cmd, err = rdr.ReadString('\n')
-		if err != nil {
-			fmt.Printf("Error reading input\n")
-			continue
-		}
-		cmd = strings.TrimSpace(cmd)
-
-		if cmd == "a" {
-			UseAnsi = !UseAnsi
-			continue
-		}
-
-		if cmd == "q" || cmd == "quit" || cmd == "exit" {
-			break
-		}
-
-		if cmd == "test" {
-			cube.PostTest()
-			continue
-		}
-
-		if cmd == "?" || cmd == "h" || cmd == "help" {
-			cube.Help()
-			rdr.ReadString('\n')
-			continue
-		}
-
-		if cmd == prevCmd || cmd == "" {
-			if cmd == "" {
-				cmd = prevCmd
+			if cube.Trace != nil {
+				cube.Trace(cube.tracePath, f, turn)
 			}
-			repeats = repeats + 1
-		} else {
-			repeats = 1
-		}
-		prevCmd = cmd
-
-		if cmd == "p" {
-			didPop := cube.Pop()
-			if !didPop {
-				cube.PrintRed("nothing to undo!\n")
+			if err := cube.Turn(f, turn); err != nil {
+				return outcome, err
 			}
-			fmt.Printf("stack size: %d\n", len(cube.History))
-			continue
 		}
-
-		if len(cmd) > 0 && cmd[0] == 'n' {
-			cube = NewCube()
-			cmd = cmd[1:]
-		}
-
-		if cmd == "n" {
-			cube = NewCube()
-			repeats = 0
-			continue
-		}
-
-		if cmd == "s" {
-			cube, cube2 = cube2, cube
-			repeats = 0
-			continue
-		}
-
-		nodes, err := cube.Parse(cmd)
-		if err != nil {
-			cube.Help()
-			msg := fmt.Sprintf("parse error. see help above: %s\n", err)
-			cube.PrintRed(msg)
-			continue
-		}
-
-		fmt.Printf("parsed as: %s\n", nodes.Print())
-		flattened, err := cube.ExecuteCommand(nodes)
-		if err != nil {
-			cube.Help()
-			msg := fmt.Sprintf("execute error. see help above: %s\n", err)
-			cube.PrintRed(msg)
-			continue
-		}
-		fmt.Printf("executed moves: %s\n", flattened)
-		fmt.Println()
-		fmt.Println()
-	}
-}
-
-func main() {
-	flag.Parse()
-	if *PostTest {
-		cube := NewCube()
-		cube.PostTest()
-	} else {
-		Loop()
 	}
+	return outcome, nil
 }
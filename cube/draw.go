@@ -0,0 +1,147 @@
+package cube
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// NetRows is the unfolded cube that Draw shows, one row of stickers at a time.
+// Every row has 11 cells: an edge of b, the l f r faces, and another edge of b.
+// An empty cell is "", and a nil row is a blank line.
+var NetRows = [][]string{
+	{"", "", "", "", "bld", "bd", "bdr", "", "", "", ""},
+	{"", "", "", "", "bl", "b", "br", "", "", "", ""},
+	{"", "", "", "", "bul", "bu", "bru", "", "", "", ""},
+	nil,
+	{"", "", "", "", "ulb", "ub", "ubr", "", "", "", ""},
+	{"", "", "", "", "ul", "u", "ur", "", "", "", ""},
+	{"", "", "", "", "ufl", "uf", "urf", "", "", "", ""},
+	nil,
+	{"bul", "lbu", "lu", "luf", "flu", "fu", "fur", "rfu", "ru", "rub", "bru"},
+	{"bl", "lb", "l", "lf", "fl", "f", "fr", "rf", "r", "rb", "br"},
+	{"bld", "ldb", "ld", "lfd", "fdl", "fd", "frd", "rdf", "rd", "rbd", "bdr"},
+	nil,
+	{"", "", "", "", "dlf", "df", "dfr", "", "", "", ""},
+	{"", "", "", "", "dl", "d", "dr", "", "", "", ""},
+	{"", "", "", "", "dbl", "db", "drb", "", "", "", ""},
+	nil,
+	{"", "", "", "", "bld", "bd", "bdr", "", "", "", ""},
+	nil,
+}
+
+// netGap is true for the cells that have a gap before them,
+// between the b edge and the faces
+func netGap(i int) bool {
+	return i == 1 || i == 4 || i == 7 || i == 10
+}
+
+// NetLines draws a cube as the lines of its net, and how wide they are
+func (style *Style) NetLines(cube *Cube) ([]string, int, error) {
+	cell := 2
+	if style.Labels != "none" {
+		cell = style.labelWidth()
+	}
+	shown := style.Shown(cube)
+	changed := cube.Changed()
+	var labels map[string]string
+	if style.Labels != "none" {
+		labels = style.stickerLabels(cube)
+	}
+	lines := make([]string, 0, len(NetRows))
+	for _, row := range NetRows {
+		if row == nil {
+			lines = append(lines, "")
+			continue
+		}
+		line := ""
+		for i, x := range row {
+			if netGap(i) {
+				line += "  "
+			}
+			if x == "" {
+				line += style.blankCell()
+				continue
+			}
+			v := shown.Stickers[x]
+			if v == "" {
+				return nil, 0, fmt.Errorf("sticker is not mapped: %s", x)
+			}
+			if style.Labels != "none" {
+				line += style.labeled(v, labels[x], changed[x])
+			} else {
+				line += style.highlighted(v, changed[x])
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines, 11*cell + 8, nil
+}
+
+// Draw writes the nets of cubes side by side, columns characters wide at
+// most, with captions under them if there are any
+func (style *Style) Draw(w io.Writer, cubes []*Cube, captions []string, columns int) error {
+	blocks := make([][]string, 0, len(cubes))
+	width := 0
+	for _, cube := range cubes {
+		lines, n, err := style.NetLines(cube)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, lines)
+		width = n
+	}
+	return WriteBlocks(w, blocks, width, captions, columns)
+}
+
+// the space between cubes, in characters
+const blockSep = 6
+
+// WriteBlocks writes blocks of lines side by side, as many as fit in
+// columns, with a caption under each, cut to width. Every line of a block
+// is width characters wide when it is shown, and an empty line is blank.
+// With nil captions there is no caption line.
+func WriteBlocks(w io.Writer, blocks [][]string, width int, captions []string, columns int) error {
+	perRow := (columns + blockSep) / (width + blockSep)
+	if perRow < 1 {
+		perRow = 1
+	}
+	sep := strings.Repeat(" ", blockSep)
+	var b strings.Builder
+	for start := 0; start < len(blocks); start += perRow {
+		end := start + perRow
+		if end > len(blocks) {
+			end = len(blocks)
+		}
+		if start > 0 {
+			b.WriteString("\n")
+		}
+		for i := range blocks[start] {
+			for _, block := range blocks[start:end] {
+				line := block[i]
+				if line == "" {
+					line = strings.Repeat(" ", width)
+				}
+				fmt.Fprintf(&b, "%s%s", line, sep)
+			}
+			b.WriteString("\n")
+		}
+		if captions == nil {
+			continue
+		}
+		for j := start; j < end; j++ {
+			// a missing caption is blank, and a long one is cut to the block
+			c := ""
+			if j < len(captions) {
+				c = captions[j]
+			}
+			if r := []rune(c); len(r) > width {
+				c = string(r[:width])
+			}
+			fmt.Fprintf(&b, "%-*s%s", width, c, sep)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
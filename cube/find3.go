@@ -0,0 +1,197 @@
+package cube
+
+import (
+	"fmt"
+)
+
+/*
+  find3 looks for a 3-cycle written the way this project likes to write
+  moves: {setup [X Y]}.
+
+  A commutator [X Y] is a 3-cycle when X moves exactly one piece into the
+  layer that Y turns. So the search makes a table of every commutator that
+  only cycles 3 pieces, with X a few moves long and Y a single turn.
+  Then for every short setup S, the target is conjugated by S and looked up
+  in the table. A cycle a b c means that the sticker at a goes to b,
+  the one at b goes to c, and the one at c comes back to a.
+*/
+
+// longest X in a commutator, and longest setup
+const find3MaxInsert = 4
+const find3MaxSetup = 3
+
+type commutator struct {
+	X []move
+	Y move
+	// Swapped commutators are [Y X], which is the inverse of [X Y]
+	Swapped bool
+}
+
+func (c commutator) Len() int {
+	return 2 * (len(c.X) + 1)
+}
+
+func (c commutator) Node() Node {
+	x := movesNode(c.X)
+	y := c.Y.Node()
+	arr := []Node{x, y}
+	if c.Swapped {
+		arr = []Node{y, x}
+	}
+	return Node{Commutator: true, Arr: arr, Repeat: 1}
+}
+
+// commutator tables by number of stickers on the pieces cycled
+var find3Tables = make(map[int]map[Perm]commutator)
+
+// find3Table has the shortest commutator for every 3-cycle of pieces with n stickers
+func find3Table(n int) map[Perm]commutator {
+	if table, ok := find3Tables[n]; ok {
+		return table
+	}
+	moves := faceMoves()
+	inverses := make([]Perm, len(moves))
+	for i := range moves {
+		inverses[i] = moves[i].Perm.Inverse()
+	}
+	table := make(map[Perm]commutator)
+	keep := func(p Perm, c commutator) {
+		if p.Moved() != 3*n {
+			return
+		}
+		for i := range p {
+			if int(p[i]) != i && len(StickerKeys[i]) != n {
+				return
+			}
+		}
+		if found, ok := table[p]; !ok || c.Len() < found.Len() {
+			table[p] = c
+		}
+	}
+	var walk func(x []move, xp Perm, xinv Perm)
+	walk = func(x []move, xp Perm, xinv Perm) {
+		if len(x) > 0 {
+			for i, y := range moves {
+				p := xp.Then(y.Perm).Then(xinv).Then(inverses[i])
+				if p.Moved() == 3*n {
+					// copy x, as walk keeps appending to it
+					c := commutator{X: append([]move(nil), x...), Y: y}
+					keep(p, c)
+					c.Swapped = true
+					keep(p.Inverse(), c)
+				}
+			}
+		}
+		if len(x) == find3MaxInsert {
+			return
+		}
+		for i, m := range moves {
+			if canFollow(x, m) {
+				walk(append(x, m), xp.Then(m.Perm), inverses[i].Then(xinv))
+			}
+		}
+	}
+	walk(nil, IdentityPerm(), IdentityPerm())
+	find3Tables[n] = table
+	return table
+}
+
+// find3Target is the permutation that cycles a to b to c, with the rest of their pieces
+func find3Target(a, b, c string) (Perm, error) {
+	names := []string{a, b, c}
+	for _, k := range names {
+		if _, ok := stickerIndex[k]; !ok {
+			return Perm{}, fmt.Errorf("%s is not a sticker. corners go clockwise, like urf", k)
+		}
+		if len(k) == 1 {
+			return Perm{}, fmt.Errorf("%s is a center, and centers do not cycle", k)
+		}
+		if len(k) != len(a) {
+			return Perm{}, fmt.Errorf("%s and %s are not the same kind of piece", a, k)
+		}
+	}
+	pieces := [][]string{pieceOf(a), pieceOf(b), pieceOf(c)}
+	for i := range pieces {
+		for j := 0; j < i; j++ {
+			for _, k := range pieces[i] {
+				if k == names[j] {
+					return Perm{}, fmt.Errorf("%s and %s are the same piece", names[j], names[i])
+				}
+			}
+		}
+	}
+	p := IdentityPerm()
+	for n := range pieces {
+		from := pieces[n]
+		to := pieces[(n+1)%len(pieces)]
+		for i := range from {
+			p[stickerIndex[to[i]]] = uint8(stickerIndex[from[i]])
+		}
+	}
+	return p, nil
+}
+
+// Find3 searches for a short {setup [X Y]} that 3-cycles the stickers a, b and c,
+// along with the rest of their pieces, and leaves everything else alone.
+// It returns the expression and the moves it flattens to.
+func (cube *Cube) Find3(a, b, c string) (string, string, error) {
+	target, err := find3Target(a, b, c)
+	if err != nil {
+		return "", "", err
+	}
+	table := find3Table(len(a))
+
+	moves := faceMoves()
+	var best *commutator
+	var bestSetup []move
+	var walk func(setup []move, sp Perm, sinv Perm)
+	walk = func(setup []move, sp Perm, sinv Perm) {
+		if found, ok := table[sinv.Then(target).Then(sp)]; ok {
+			if best == nil || found.Len()+2*len(setup) < best.Len()+2*len(bestSetup) {
+				best = &found
+				bestSetup = append([]move(nil), setup...)
+			}
+		}
+		if len(setup) == find3MaxSetup {
+			return
+		}
+		for _, m := range moves {
+			if canFollow(setup, m) {
+				walk(append(setup, m), sp.Then(m.Perm), m.Perm.Inverse().Then(sinv))
+			}
+		}
+	}
+	walk(nil, IdentityPerm(), IdentityPerm())
+	if best == nil {
+		return "", "", fmt.Errorf(
+			"no 3-cycle of %s %s %s within %d setup moves and %d move inserts",
+			a, b, c, find3MaxSetup, find3MaxInsert,
+		)
+	}
+
+	node := best.Node()
+	if len(bestSetup) > 0 {
+		node = Node{
+			Commutator: true,
+			Conjugated: true,
+			Arr:        []Node{movesNode(bestSetup), node},
+			Repeat:     1,
+		}
+	}
+	found := node.Print()
+
+	// verify it by executing what was printed, the way a user would type it
+	parsed, err := cube.Parse(found)
+	if err != nil {
+		return found, "", fmt.Errorf("found %s, but it does not parse: %s", found, err)
+	}
+	scratch := labeledCube()
+	flattened, err := scratch.Execute(parsed, 0, 0, 0, 0, 0)
+	if err != nil {
+		return found, "", fmt.Errorf("found %s, but it does not execute: %s", found, err)
+	}
+	if labeledPerm(scratch) != target {
+		return found, flattened, fmt.Errorf("found %s, but it does not cycle %s %s %s", found, a, b, c)
+	}
+	return found, flattened, nil
+}
@@ -0,0 +1,238 @@
+package cube
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"strings"
+)
+
+/*
+  A gif replays an expression one quarter turn at a time, from the moves
+  that Execute flattens it to. Every frame shows the cube after a turn,
+  with the stickers of the layer that turned outlined, and the turn written
+  under it. It uses the same layout and sizes as the svg export. A gif
+  can have gifFrameMax frames, because every one of them is kept in memory
+  until the gif is written.
+
+  The standard library has no fonts, so the few characters that a move
+  label needs are drawn from a small bitmap font.
+*/
+
+// glyphs are 5x7 pixels, for the characters that move labels use
+var glyphs = map[rune][7]string{
+	'0': {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1': {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2': {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3': {"####.", "....#", "....#", ".###.", "....#", "....#", "####."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5': {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6': {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8': {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9': {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+	'/': {"....#", "....#", "...#.", "..#..", ".#...", "#....", "#...."},
+	'u': {".....", ".....", "#...#", "#...#", "#...#", "#..##", ".##.#"},
+	'r': {".....", ".....", "#.##.", "##..#", "#....", "#....", "#...."},
+	'f': {"..##.", ".#..#", ".#...", "###..", ".#...", ".#...", ".#..."},
+	'd': {"....#", "....#", ".##.#", "#..##", "#...#", "#...#", ".####"},
+	'l': {".##..", "..#..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'b': {"#....", "#....", "#.##.", "##..#", "#...#", "#...#", "####."},
+	'U': {"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'R': {"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#"},
+	'F': {"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+	'D': {"###..", "#..#.", "#...#", "#...#", "#...#", "#..#.", "###.."},
+	'L': {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'B': {"####.", "#...#", "#...#", "####.", "#...#", "#...#", "####."},
+}
+
+const gifTextScale = 2
+
+// the most quarter turns that a gif replays
+const gifFrameMax = 500
+
+// hexColor reads colors like #ff5800
+func hexColor(s string) (color.RGBA, error) {
+	r, g, b, err := rgbOf(s)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	return color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 255}, nil
+}
+
+// palette indexes for gif frames
+const (
+	gifBackground = iota
+	gifBorder
+	gifHighlight
+	gifText
+	gifFaces
+)
+
+func (style *Style) gifPalette(cube *Cube) (color.Palette, map[string]uint8, error) {
+	palette := color.Palette{}
+	faces := make(map[string]uint8)
+	// the fixed colors come first, in the order of their indexes
+	names := []string{"#202020", "#000000", "#ff00ff", "#ffffff"}
+	for _, f := range cube.Faces {
+		faces[f] = uint8(len(names))
+		names = append(names, style.FaceRGB[f])
+	}
+	faces[maskedSuffix] = uint8(len(names))
+	names = append(names, MaskedRGB)
+	for _, name := range names {
+		c, err := hexColor(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		palette = append(palette, c)
+	}
+	return palette, faces, nil
+}
+
+func fillRect(img *image.Paletted, x, y, w, h int, c uint8) {
+	for j := y; j < y+h; j++ {
+		for i := x; i < x+w; i++ {
+			img.SetColorIndex(i, j, c)
+		}
+	}
+}
+
+// drawText writes with the bitmap font, skipping characters that it does not have
+func drawText(img *image.Paletted, x, y int, text string, c uint8) {
+	for _, ch := range text {
+		for row, line := range glyphs[ch] {
+			for col, px := range line {
+				if px == '#' {
+					fillRect(img, x+col*gifTextScale, y+row*gifTextScale, gifTextScale, gifTextScale, c)
+				}
+			}
+		}
+		x += 6 * gifTextScale
+	}
+}
+
+// layerOf is the stickers that a turn of face moves, which Turn1 says,
+// and the center of the face. A turn of the whole cube moves all of them.
+func layerOf(face string) (map[string]bool, error) {
+	turned := labeledCube()
+	if err := turned.Turn(face, 1); err != nil {
+		return nil, err
+	}
+	layer := map[string]bool{strings.ToLower(face): true}
+	for k, v := range turned.Stickers {
+		if k != v || turned.shouldTurnWholeCube(face) {
+			layer[k] = true
+		}
+	}
+	return layer, nil
+}
+
+// gifFrame draws the net of a cube, outlining the stickers in layer
+func (style *Style) gifFrame(cube *Cube, palette color.Palette, faces map[string]uint8, layer map[string]bool, label string) (*image.Paletted, error) {
+	cube = style.Shown(cube)
+	netW, netH := svgNetSize()
+	img := image.NewPaletted(
+		image.Rect(0, 0, 2*svgMargin+netW, 3*svgMargin+netH+7*gifTextScale),
+		palette,
+	)
+	fillRect(img, 0, 0, img.Rect.Dx(), img.Rect.Dy(), gifBackground)
+	y := svgMargin
+	for _, row := range NetRows {
+		if row == nil {
+			y += svgBlank
+			continue
+		}
+		x := svgMargin
+		for i, k := range row {
+			if netGap(i) {
+				x += svgGap
+			}
+			if k != "" {
+				v := cube.Stickers[k]
+				if isMasked(v) {
+					v = maskedSuffix
+				}
+				c, ok := faces[v]
+				if !ok {
+					return nil, fmt.Errorf("sticker is not mapped: %s", k)
+				}
+				edge := uint8(gifBorder)
+				if layer[k] {
+					edge = gifHighlight
+				}
+				fillRect(img, x, y, svgCell, svgCell, edge)
+				fillRect(img, x+2, y+2, svgCell-4, svgCell-4, c)
+			}
+			x += svgCell
+		}
+		y += svgCell
+	}
+	drawText(img, svgMargin, y+svgMargin, label, gifText)
+	return img, nil
+}
+
+// GIF replays a command from the current state of the cube, one quarter
+// turn per frame, without changing the cube.
+func (style *Style) GIF(w io.Writer, cube *Cube, node Node) error {
+	scratch := NewCube()
+	for k, v := range cube.Stickers {
+		scratch.Stickers[k] = v
+	}
+	flattened, err := NewCube().Execute(node, 0, 0, 0, 0, 0)
+	if err != nil {
+		return err
+	}
+	turns, err := ParseTurns(flattened)
+	if err != nil {
+		return err
+	}
+	// a counter-clockwise turn is one frame, and a half turn is two
+	quarters := make([]Turn, 0)
+	for _, t := range turns {
+		switch t.Amount {
+		case 1, 3:
+			quarters = append(quarters, t)
+		case 2:
+			quarters = append(quarters, Turn{Face: t.Face, Amount: 1}, Turn{Face: t.Face, Amount: 1})
+		}
+	}
+
+	if len(quarters) > gifFrameMax {
+		return fmt.Errorf("%s is %d quarter turns, and a gif can show %d at most", node.Print(), len(quarters), gifFrameMax)
+	}
+
+	palette, faces, err := style.gifPalette(cube)
+	if err != nil {
+		return err
+	}
+	anim := &gif.GIF{}
+	frame, err := style.gifFrame(scratch, palette, faces, nil, fmt.Sprintf("0/%d", len(quarters)))
+	if err != nil {
+		return err
+	}
+	anim.Image = append(anim.Image, frame)
+	anim.Delay = append(anim.Delay, 100)
+	layers := make(map[string]map[string]bool)
+	for i, t := range quarters {
+		if err := scratch.Turn(t.Face, t.Amount); err != nil {
+			return err
+		}
+		if layers[t.Face] == nil {
+			if layers[t.Face], err = layerOf(t.Face); err != nil {
+				return err
+			}
+		}
+		label := fmt.Sprintf("%d/%d %s", i+1, len(quarters), TurnsString([]Turn{t}))
+		frame, err := style.gifFrame(scratch, palette, faces, layers[t.Face], label)
+		if err != nil {
+			return err
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 60)
+	}
+	anim.Delay[len(anim.Delay)-1] = 200
+	return gif.EncodeAll(w, anim)
+}
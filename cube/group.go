@@ -0,0 +1,211 @@
+package cube
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+/*
+  Generators are moves that a search is allowed to use, or that
+  generate a subgroup. They are written as expressions, separated by commas:
+
+    [fr],u
+
+  A list of plain face names like ru is short for r,u.
+
+  The order of the group that some generators make is found with the
+  Schreier-Sims algorithm, in the form that Knuth wrote up in
+  "Efficient representation of perm groups". It keeps, for every sticker
+  location k, a table of group elements that fix every location after k,
+  and move k to each place that it can go. The order is the product of the
+  sizes of those tables.
+*/
+
+// Generators splits a list of generators into expressions
+func Generators(gen string) ([]string, error) {
+	gen = strings.TrimSpace(gen)
+	gen = strings.TrimPrefix(gen, "<")
+	gen = strings.TrimSuffix(gen, ">")
+	exprs := make([]string, 0)
+	switch {
+	case strings.Contains(gen, ","):
+		exprs = strings.Split(gen, ",")
+	case onlyFaces(gen):
+		for _, f := range gen {
+			exprs = append(exprs, string(f))
+		}
+	default:
+		exprs = strings.Fields(gen)
+	}
+	for i := range exprs {
+		exprs[i] = strings.TrimSpace(exprs[i])
+		if exprs[i] == "" {
+			return nil, fmt.Errorf("empty generator in %s", gen)
+		}
+	}
+	if len(exprs) == 0 {
+		return nil, fmt.Errorf("no generators given")
+	}
+	return exprs, nil
+}
+
+// onlyFaces is true for a list of face names, like ru
+func onlyFaces(gen string) bool {
+	if gen == "" {
+		return false
+	}
+	for _, f := range gen {
+		if !strings.ContainsRune("urfdlbURFDLB", f) {
+			return false
+		}
+	}
+	return true
+}
+
+// generatorNode parses a generator, as one item that can be negated or repeated
+func generatorNode(expr string) (Node, error) {
+	node, err := NewCube().Parse(expr)
+	if err != nil {
+		return Node{}, fmt.Errorf("generator %s: %s", expr, err)
+	}
+	if len(node.Arr) == 0 {
+		return Node{}, fmt.Errorf("generator %s does nothing", expr)
+	}
+	item := node.Arr[0]
+	if len(node.Arr) > 1 || node.Reflection != "" || item.Repeat > 1 {
+		item = Node{Arr: node.Arr, Reflection: node.Reflection, Repeat: 1}
+	}
+	return item, nil
+}
+
+// expressionMoves are every power of the generators that is not the
+// identity, written the short way round: [fr] [fr]2 [fr]3 /[fr]2 /[fr].
+// Any power that does the same thing as one before it is left out.
+func expressionMoves(exprs []string) ([]move, error) {
+	moves := make([]move, 0)
+	seen := map[Perm]bool{IdentityPerm(): true}
+	for i, expr := range exprs {
+		item, err := generatorNode(expr)
+		if err != nil {
+			return nil, err
+		}
+		g, err := NewCube().PermOf(Node{Arr: []Node{item}})
+		if err != nil {
+			return nil, fmt.Errorf("generator %s: %s", expr, err)
+		}
+		order := g.Order()
+		for k := 1; k < order; k++ {
+			n, turn := item, k
+			if k > order/2 {
+				n.Negate, turn = !n.Negate, k-order
+			}
+			if turn > 1 || turn < -1 {
+				n.Repeat = turn
+				if turn < 0 {
+					n.Repeat = -turn
+				}
+			}
+			p, err := NewCube().PermOf(Node{Arr: []Node{n}})
+			if err != nil {
+				return nil, fmt.Errorf("generator %s: %s", expr, err)
+			}
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			moves = append(moves, move{Name: n.Print(), Perm: p, Face: i, Axis: i, Turn: turn, Item: n})
+		}
+	}
+	return moves, nil
+}
+
+// schreierSims is a table of group elements by level, where sigma[k][j]
+// fixes every location after k, and moves location k to j
+type schreierSims struct {
+	sigma    [StickerCount][StickerCount]*Perm
+	sigmaInv [StickerCount][StickerCount]*Perm
+	gens     [StickerCount][]Perm
+}
+
+// after does p and then q, as maps from location to location
+func after(p Perm, q Perm) Perm {
+	return q.Then(p)
+}
+
+// member sifts p down through the levels, and is true if p is in the group
+func (ss *schreierSims) member(k int, p Perm) bool {
+	for ; k >= 0; k-- {
+		j := p[k]
+		if ss.sigma[k][j] == nil {
+			return false
+		}
+		p = after(p, *ss.sigmaInv[k][j])
+	}
+	return true
+}
+
+// add puts p, which fixes every location after k, into the group at level k
+func (ss *schreierSims) add(k int, p Perm) {
+	if k < 0 || ss.member(k, p) {
+		return
+	}
+	ss.gens[k] = append(ss.gens[k], p)
+	reps := make([]Perm, 0)
+	for j := range ss.sigma[k] {
+		if ss.sigma[k][j] != nil {
+			reps = append(reps, *ss.sigma[k][j])
+		}
+	}
+	for _, s := range reps {
+		ss.extend(k, after(s, p))
+	}
+}
+
+// extend finds where p moves location k, and fills in the table at level k,
+// or pushes what is left of p down to the next level
+func (ss *schreierSims) extend(k int, p Perm) {
+	j := p[k]
+	if ss.sigma[k][j] == nil {
+		inv := p.Inverse()
+		ss.sigma[k][j] = &p
+		ss.sigmaInv[k][j] = &inv
+		for _, t := range ss.gens[k] {
+			ss.extend(k, after(p, t))
+		}
+		return
+	}
+	ss.add(k-1, after(p, *ss.sigmaInv[k][j]))
+}
+
+// GroupOrder is the number of positions reachable with the generators
+func (cube *Cube) GroupOrder(exprs []string) (*big.Int, error) {
+	ss := &schreierSims{}
+	id := IdentityPerm()
+	for k := range ss.sigma {
+		ss.sigma[k][k] = &id
+		ss.sigmaInv[k][k] = &id
+	}
+	for _, expr := range exprs {
+		item, err := generatorNode(expr)
+		if err != nil {
+			return nil, err
+		}
+		p, err := cube.PermOf(Node{Arr: []Node{item}})
+		if err != nil {
+			return nil, fmt.Errorf("generator %s: %s", expr, err)
+		}
+		ss.add(StickerCount-1, p)
+	}
+	order := big.NewInt(1)
+	for k := range ss.sigma {
+		n := 0
+		for j := range ss.sigma[k] {
+			if ss.sigma[k][j] != nil {
+				n++
+			}
+		}
+		order.Mul(order, big.NewInt(int64(n)))
+	}
+	return order, nil
+}
@@ -0,0 +1,84 @@
+package cube
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+  Draw can mark the stickers that the last command changed, by comparing
+  them with the state in Before. After an undo nothing is marked:
+
+    border  brackets around the sticker
+    blink   the sticker blinks
+    mark    a * on the sticker
+
+  Without ansi colors, every style is a * after the sticker.
+*/
+
+var HighlightStyles = []string{"none", "border", "blink", "mark"}
+
+// Changed are the stickers that the last command changed
+func (cube *Cube) Changed() map[string]bool {
+	changed := make(map[string]bool)
+	if cube.Before == nil {
+		return changed
+	}
+	now := cube.State()
+	for i, k := range StickerKeys {
+		if cube.Before[i] != now[i] {
+			changed[k] = true
+		}
+	}
+	return changed
+}
+
+// Diff lists the stickers that differ from the other cube, in index order
+func (cube *Cube) Diff(other *Cube) []string {
+	keys := make([]string, 0)
+	for k, v := range cube.Stickers {
+		if other.Stickers[k] != v {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return stickerIndex[keys[i]] < stickerIndex[keys[j]] })
+	return keys
+}
+
+// DiffString says how the cubes differ, one sticker per line: uf f r
+func (style *Style) DiffString(cube *Cube, other *Cube) string {
+	keys := cube.Diff(other)
+	if len(keys) == 0 {
+		return "the cubes are the same\n"
+	}
+	pieces := make(map[string]bool)
+	var b strings.Builder
+	for _, k := range keys {
+		names := pieceOf(k)
+		sort.Strings(names)
+		pieces[names[0]] = true
+		fmt.Fprintf(&b, "%-4s %s %s\n",
+			k, style.FaceColored(cube.Stickers[k], cube.Stickers[k]), style.FaceColored(other.Stickers[k], other.Stickers[k]))
+	}
+	fmt.Fprintf(&b, "%d stickers on %d pieces differ\n", len(keys), len(pieces))
+	return b.String()
+}
+
+// highlighted draws a sticker block, changed or not
+func (style *Style) highlighted(v string, changed bool) string {
+	if !changed || style.Highlight == "none" {
+		return style.StickerBlock(v)
+	}
+	if !style.Ansi {
+		return stickerText(v) + "*"
+	}
+	rgb := style.stickerRGB(v)
+	switch style.Highlight {
+	case "border":
+		return fmt.Sprintf("\u001b[1;%s;%sm[]\u001b[0m", style.colorEscape(rgb, true), style.textOn(rgb))
+	case "blink":
+		return fmt.Sprintf("\u001b[1;5;%s;%sm%s \u001b[0m", style.colorEscape(rgb, true), style.textOn(rgb), style.overlayOf(v))
+	}
+	return fmt.Sprintf("\u001b[1;%s;%sm%s*\u001b[0m", style.colorEscape(rgb, true), style.textOn(rgb), style.overlayOf(v))
+}
@@ -0,0 +1,276 @@
+package cube
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+)
+
+/*
+  The isometric view shows the cube as it looks in your hand: the u, f and r
+  faces from the front, and the d, l and b faces from the opposite corner.
+  The opposite corner is drawn by turning a copy of the cube so that d is up,
+  l is in front and b is on the right, and drawing it like the front.
+
+  In the terminal, the faces are sheared so that every step back is one row
+  up and two columns right:
+
+        uuuuuuuuuuuu r    back row of u
+      uuuuuuuuuuuu rr
+    uuuuuuuuuuuu rrr      front row of u
+    ffffffffffff rrr
+    ...
+*/
+
+// the stickers of the faces that the front view shows, as they are seen
+var isoTop = [3][3]string{{"ulb", "ub", "ubr"}, {"ul", "u", "ur"}, {"ufl", "uf", "urf"}}
+var isoFront = [3][3]string{{"flu", "fu", "fur"}, {"fl", "f", "fr"}, {"fdl", "fd", "frd"}}
+var isoRight = [3][3]string{{"rfu", "ru", "rub"}, {"rf", "r", "rb"}, {"rdf", "rd", "rbd"}}
+
+// the terminal view size in characters
+const isoWidth = 18
+const isoHeight = 9
+
+// oppositeTurns are the whole cube turns that make the opposite view
+var oppositeTurns string
+
+// makeOppositeTurns tries whole cube turns until the centers are in the right place
+func makeOppositeTurns() error {
+	candidates := []string{""}
+	for len(candidates) > 0 && oppositeTurns == "" {
+		next := make([]string, 0)
+		for _, c := range candidates {
+			for _, t := range []string{"U", "R", "F"} {
+				turns := c + t
+				scratch := NewCube()
+				if err := scratch.Apply(turns); err != nil {
+					return err
+				}
+				if scratch.Stickers["u"] == "d" && scratch.Stickers["f"] == "l" && scratch.Stickers["r"] == "b" {
+					oppositeTurns = turns
+				}
+				next = append(next, turns)
+			}
+		}
+		candidates = next
+	}
+	return nil
+}
+
+// oppositeView is a copy of the cube, turned so that d is up, l is in front and b is on the right
+func oppositeView(cube *Cube) (*Cube, error) {
+	turned := cube.Clone()
+	if err := turned.Apply(oppositeTurns); err != nil {
+		return nil, err
+	}
+	return turned, nil
+}
+
+// isoCanvas places the stickers of the front view onto characters.
+// Every character knows its sticker, and whether it is on the right or
+// bottom edge of it, so that stickers can be drawn with gaps between them.
+// The first character of a sticker gets the color-blind overlay.
+type isoChar struct {
+	Key    string
+	First  bool
+	Right  bool
+	Bottom bool
+}
+
+func isoCanvas() [isoHeight][isoWidth]isoChar {
+	var canvas [isoHeight][isoWidth]isoChar
+	put := func(key string, x, y, w, h int) {
+		for j := 0; j < h; j++ {
+			for i := 0; i < w; i++ {
+				canvas[y+j][x+i] = isoChar{Key: key, First: i == 0 && j == 0, Right: i == w-1, Bottom: j == h-1}
+			}
+		}
+	}
+	for row := 0; row < 3; row++ {
+		depth := 2 - row
+		for col := 0; col < 3; col++ {
+			put(isoTop[row][col], 2+2*depth+4*col, 2-depth, 4, 1)
+		}
+	}
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			put(isoFront[row][col], 4*col, 3+2*row, 4, 2)
+		}
+	}
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			put(isoRight[row][col], 12+2*col, 3+2*row-col, 2, 2)
+		}
+	}
+	return canvas
+}
+
+// isoLines draws one view of a cube as lines of text
+func (style *Style) isoLines(cube *Cube) ([]string, error) {
+	canvas := isoCanvas()
+	lines := make([]string, 0, isoHeight)
+	for _, row := range canvas {
+		line := ""
+		for _, c := range row {
+			if c.Key == "" {
+				line += " "
+				continue
+			}
+			v := cube.Stickers[c.Key]
+			if v == "" {
+				return nil, fmt.Errorf("sticker is not mapped: %s", c.Key)
+			}
+			if !style.Ansi {
+				if c.Right || c.Bottom && c.Key[0] != 'u' {
+					line += " "
+				} else {
+					line += stickerText(v)
+				}
+				continue
+			}
+			if c.First && style.Colors.Overlay != "none" {
+				rgb := style.stickerRGB(v)
+				line += fmt.Sprintf("\u001b[1;%s;%sm%s\u001b[0m", style.colorEscape(rgb, true), style.textOn(rgb), style.overlayOf(v))
+				continue
+			}
+			block := "█"
+			switch {
+			case c.Right && c.Bottom:
+				block = "▘"
+			case c.Right:
+				block = "▌"
+			case c.Bottom:
+				block = "▀"
+			}
+			line += style.FaceColored(v, block)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// IsoLines draws the front and opposite views of a cube side by side, as
+// lines of text, and how wide they are
+func (style *Style) IsoLines(cube *Cube) ([]string, int, error) {
+	cube = style.Shown(cube)
+	opposite, err := oppositeView(cube)
+	if err != nil {
+		return nil, 0, err
+	}
+	front, err := style.isoLines(cube)
+	if err != nil {
+		return nil, 0, err
+	}
+	back, err := style.isoLines(opposite)
+	if err != nil {
+		return nil, 0, err
+	}
+	lines := make([]string, 0, isoHeight+1)
+	for i := 0; i < isoHeight; i++ {
+		lines = append(lines, "  "+front[i]+"    "+"  "+back[i])
+	}
+	lines = append(lines, fmt.Sprintf("  %-*s    %-*s", isoWidth, "      u f r", isoWidth+2, "        d l b"))
+	return lines, 2*isoWidth + 8, nil
+}
+
+// DrawIso writes the front and opposite views of cubes side by side, like Draw
+func (style *Style) DrawIso(w io.Writer, cubes []*Cube, captions []string, columns int) error {
+	blocks := make([][]string, 0, len(cubes))
+	width := 0
+	for _, cube := range cubes {
+		lines, n, err := style.IsoLines(cube)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, lines)
+		width = n
+	}
+	return WriteBlocks(w, blocks, width, captions, columns)
+}
+
+// the svg view is a true isometric projection, in pixels per sticker
+const isoSVGScale = 24
+
+// isoPoint projects a point of the cube, where x is right, y is up and z is back
+func isoPoint(x, y, z float64) (float64, float64) {
+	c := math.Cos(math.Pi / 6)
+	return (x + z) * c * isoSVGScale, ((x-z)*0.5 - y) * isoSVGScale
+}
+
+// IsoSVG draws the isometric views of cubes as an svg image
+func (style *Style) IsoSVG(w io.Writer, cubes []*Cube, caption string) error {
+	viewW := 6 * math.Cos(math.Pi/6) * isoSVGScale
+	viewH := 6.0 * isoSVGScale
+	width := 2*svgMargin + int(math.Ceil(float64(2*len(cubes))*(viewW+svgCell)))
+	height := 2*svgMargin + int(viewH) + svgCell
+	if caption != "" {
+		height += svgCell
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", width, height, width, height)
+	fmt.Fprintf(&b, "<rect width=\"%d\" height=\"%d\" fill=\"#202020\"/>\n", width, height)
+	view := 0
+	for _, cube := range cubes {
+		cube = style.Shown(cube)
+		opposite, err := oppositeView(cube)
+		if err != nil {
+			return err
+		}
+		for _, shown := range []*Cube{cube, opposite} {
+			// the top corner of the view is at (0,3,3), which projects to y = -4.5
+			left := float64(svgMargin) + float64(view)*(viewW+svgCell)
+			top := float64(svgMargin) + 4.5*isoSVGScale
+			poly := func(key string, corners [4][3]float64) error {
+				v := shown.Stickers[key]
+				if style.stickerRGB(v) == "" {
+					return fmt.Errorf("sticker is not mapped: %s", key)
+				}
+				points := make([]string, 0, 4)
+				for _, p := range corners {
+					x, y := isoPoint(p[0], p[1], p[2])
+					points = append(points, fmt.Sprintf("%.1f,%.1f", left+x, top+y))
+				}
+				fmt.Fprintf(&b,
+					"<polygon points=\"%s\" fill=\"%s\" stroke=\"#000000\" stroke-width=\"2\"><title>%s</title></polygon>\n",
+					strings.Join(points, " "), style.stickerRGB(v), key,
+				)
+				return nil
+			}
+			for row := 0; row < 3; row++ {
+				for col := 0; col < 3; col++ {
+					x, z := float64(col), float64(2-row)
+					if err := poly(isoTop[row][col], [4][3]float64{
+						{x, 3, z}, {x + 1, 3, z}, {x + 1, 3, z + 1}, {x, 3, z + 1},
+					}); err != nil {
+						return err
+					}
+					x, y := float64(col), float64(3-row)
+					if err := poly(isoFront[row][col], [4][3]float64{
+						{x, y, 0}, {x + 1, y, 0}, {x + 1, y - 1, 0}, {x, y - 1, 0},
+					}); err != nil {
+						return err
+					}
+					z, y = float64(col), float64(3-row)
+					if err := poly(isoRight[row][col], [4][3]float64{
+						{3, y, z}, {3, y, z + 1}, {3, y - 1, z + 1}, {3, y - 1, z},
+					}); err != nil {
+						return err
+					}
+				}
+			}
+			view++
+		}
+	}
+	if caption != "" {
+		fmt.Fprintf(&b,
+			"<text x=\"%d\" y=\"%d\" font-family=\"monospace\" font-size=\"14\" fill=\"#ffffff\">%s</text>\n",
+			svgMargin, height-svgMargin, html.EscapeString(caption),
+		)
+	}
+	fmt.Fprintf(&b, "</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,76 @@
+package cube
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+  Labels write names on the stickers in Draw, to show how they are named:
+
+    keys    the location of every sticker, like fur. Corners are named
+            clockwise from the sticker, so fur, urf and rfu are one corner.
+    pieces  the sticker that is at every location now, as its solved
+            location. A solved cube shows the same as keys.
+
+  With ansi colors, the name is written on the color. Without, the color
+  is written after the name, like fur=u.
+*/
+
+var LabelModes = []string{"none", "keys", "pieces"}
+
+// labelWidth is how wide a labeled sticker is, with the space after it
+func (style *Style) labelWidth() int {
+	if style.Ansi {
+		return 5
+	}
+	return 6
+}
+
+// stickerLabels names every location of a cube for the label mode
+func (style *Style) stickerLabels(cube *Cube) map[string]string {
+	labels := make(map[string]string)
+	var tracked Perm
+	var err error
+	if style.Labels == "pieces" {
+		tracked, err = cube.Tracking()
+	}
+	for i, k := range StickerKeys {
+		switch {
+		case style.Labels == "keys":
+			labels[k] = k
+		case err != nil:
+			// the colors do not make pieces, which is what a mapping bug looks like
+			labels[k] = "?"
+		default:
+			labels[k] = StickerKeys[tracked[i]]
+		}
+	}
+	return labels
+}
+
+// labeled draws a sticker of color v with a name on it
+func (style *Style) labeled(v string, label string, changed bool) string {
+	if !style.Ansi {
+		mark := " "
+		if changed && style.Highlight != "none" {
+			mark = "*"
+		}
+		return fmt.Sprintf("%-3s=%s%s", label, stickerText(v), mark)
+	}
+	rgb := style.stickerRGB(v)
+	sgr := "1"
+	if changed && style.Highlight != "none" {
+		// every highlight style blinks here, because the name fills the sticker
+		sgr = "1;5"
+	}
+	return fmt.Sprintf("\u001b[%s;%s;%sm%-4s\u001b[0m ", sgr, style.colorEscape(rgb, true), style.textOn(rgb), label)
+}
+
+// blankCell is an empty place in the net, as wide as a sticker
+func (style *Style) blankCell() string {
+	if style.Labels == "none" {
+		return "  "
+	}
+	return strings.Repeat(" ", style.labelWidth())
+}
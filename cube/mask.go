@@ -0,0 +1,167 @@
+package cube
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+  A mask names the stickers that matter, and ignores the rest.
+  It is written as a list of sticker locations:
+
+    cross: df fd dr rd db bd dl ld
+
+  A location can be given a class, like uf=o. Stickers in the same class
+  can stand in for each other, which is how edge orientation is written:
+  it does not matter which edge is at uf, as long as its u or d sticker is.
+  A mask can also use the name of another mask, to include it.
+
+  Programs can add their own masks with ParseMasks, in the same format as
+  PresetMasks.
+*/
+
+// PresetMasks are always available, unless the masks file redefines them
+var PresetMasks = `
+-- the d cross
+cross: df fd dr rd db bd dl ld
+-- the cross, and the pair that goes in the fr slot
+f2l-fr: cross fr rf dfr frd rdf
+-- first two layers
+f2l: cross fr rf dfr frd rdf fl lf dlf lfd fdl bl lb dbl bld ldb br rb drb rbd bdr
+-- every edge has its u or d sticker in u or d, or its f or b sticker in f or b
+eo: uf=o ur=o ub=o ul=o df=o dr=o db=o dl=o fr=o fl=o br=o bl=o
+-- the u layer
+lastlayer: u uf fu ur ru ub bu ul lu urf rfu fur ubr bru rub ulb lbu bul ufl flu luf
+-- the roux first block, on the left
+firstblock: l dl ld lf fl lb bl dlf lfd fdl dbl bld ldb
+`
+
+// Mask maps the locations that must be solved to their class.
+// Unless a class is given, the class is the location itself.
+type Mask struct {
+	Name  string
+	Class map[string]string
+}
+
+// Keys are the locations in the mask, in index order
+func (mask Mask) Keys() []string {
+	keys := make([]string, 0, len(mask.Class))
+	for k := range mask.Class {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return stickerIndex[keys[i]] < stickerIndex[keys[j]] })
+	return keys
+}
+
+// Solved says whether every location in the mask has a sticker of its class
+func (mask Mask) Solved(state Perm) bool {
+	for k, class := range mask.Class {
+		at := StickerKeys[state[stickerIndex[k]]]
+		if got, ok := mask.Class[at]; !ok || got != class {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseMasks reads mask definitions, one per line, adding them to masks
+func ParseMasks(text string, masks map[string]Mask) error {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	line := 0
+	for scanner.Scan() {
+		line++
+		s := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(s, "--"); i >= 0 {
+			s = strings.TrimSpace(s[:i])
+		}
+		if s == "" {
+			continue
+		}
+		name, body, found := strings.Cut(s, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return fmt.Errorf("line %d: should look like name: stickers", line)
+		}
+		mask, err := MaskOf(name, strings.Fields(body), masks)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+		masks[name] = mask
+	}
+	return nil
+}
+
+// MaskOf combines stickers, stickers with a class, and other masks
+func MaskOf(name string, words []string, masks map[string]Mask) (Mask, error) {
+	mask := Mask{Name: name, Class: make(map[string]string)}
+	for _, w := range words {
+		if other, ok := masks[w]; ok {
+			for k, class := range other.Class {
+				mask.Class[k] = class
+			}
+			continue
+		}
+		k, class, found := strings.Cut(w, "=")
+		if !found {
+			class = k
+		}
+		if _, ok := stickerIndex[k]; !ok {
+			return mask, fmt.Errorf("%s is not a sticker or a mask", k)
+		}
+		if class == "" {
+			return mask, fmt.Errorf("%s needs a class after the =", k)
+		}
+		mask.Class[k] = class
+	}
+	if len(mask.Class) == 0 {
+		return mask, fmt.Errorf("mask %s has no stickers", name)
+	}
+	return mask, nil
+}
+
+// Presets are the preset masks, by name
+func Presets() (map[string]Mask, error) {
+	masks := make(map[string]Mask)
+	if err := ParseMasks(PresetMasks, masks); err != nil {
+		return masks, fmt.Errorf("preset masks: %s", err)
+	}
+	return masks, nil
+}
+
+// MaskNames lists masks for help
+func MaskNames(masks map[string]Mask) string {
+	names := make([]string, 0, len(masks))
+	for name := range masks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+// masked sticker values keep their color as the first letter, so that
+// a masked cube can still be turned
+const maskedSuffix = "-"
+
+// MaskedRGB is the color of stickers that the mask of a style hides
+var MaskedRGB = "#505050"
+
+func isMasked(v string) bool {
+	return strings.HasSuffix(v, maskedSuffix)
+}
+
+// Shown is the cube as renderers draw it, with the mask of the style applied
+func (style *Style) Shown(cube *Cube) *Cube {
+	if style.Mask == nil {
+		return cube
+	}
+	shown := NewCube()
+	for k, v := range cube.Stickers {
+		if _, ok := style.Mask.Class[k]; !ok {
+			v += maskedSuffix
+		}
+		shown.Stickers[k] = v
+	}
+	return shown
+}
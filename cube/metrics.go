@@ -0,0 +1,225 @@
+package cube
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+  Metrics count how expensive a flattened move list is:
+
+    htm  half turn metric: any turn of a face counts 1
+    qtm  quarter turn metric: a half turn counts 2
+    stm  slice turn metric: like htm, but a middle layer counts 1 when
+         whole cube turns and face turns on the same axis add up to one
+    etm  execution turn metric: every turn counts 1, whole cube turns too
+
+  Whole cube turns (upper-case faces) are not face turns. They are counted
+  separately as rotations, and only etm includes them.
+*/
+
+// Turn is one item of a flattened move list, in clockwise quarter turns 0 to 3
+type Turn struct {
+	Face   string
+	Amount int
+}
+
+type Metrics struct {
+	HTM       int
+	QTM       int
+	STM       int
+	ETM       int
+	Rotations int
+}
+
+func (m Metrics) String() string {
+	return fmt.Sprintf("htm %d qtm %d stm %d etm %d rotations %d", m.HTM, m.QTM, m.STM, m.ETM, m.Rotations)
+}
+
+// ParseTurns reads moves like Execute writes them: r /u d2
+func ParseTurns(flattened string) ([]Turn, error) {
+	turns := make([]Turn, 0)
+	for _, w := range strings.Fields(flattened) {
+		sign := 1
+		if strings.HasPrefix(w, "/") {
+			sign = -1
+			w = w[1:]
+		}
+		if w == "" || !strings.ContainsRune("urfdlbURFDLB", rune(w[0])) {
+			return nil, fmt.Errorf("not a turn: %s", w)
+		}
+		count := 1
+		if len(w) > 1 {
+			n, err := strconv.Atoi(w[1:])
+			if err != nil {
+				return nil, fmt.Errorf("not a turn: %s", w)
+			}
+			count = n
+		}
+		turns = append(turns, Turn{Face: w[:1], Amount: ((sign*count)%4 + 4) % 4})
+	}
+	return turns, nil
+}
+
+// axisOf is u for u and d, r for r and l, and f for f and b, with the
+// direction that a clockwise turn has around that axis
+func axisOf(face string) (string, int) {
+	f := strings.ToLower(face)
+	switch f {
+	case "d":
+		return "u", -1
+	case "l":
+		return "r", -1
+	case "b":
+		return "f", -1
+	}
+	return f, 1
+}
+
+// SimplifyTurns merges turns of the same face, even when there are turns
+// in between that commute with it, like the d in: u d /u
+func SimplifyTurns(turns []Turn) []Turn {
+	out := make([]Turn, 0)
+	for _, t := range turns {
+		if t.Amount == 0 {
+			continue
+		}
+		axis, _ := axisOf(t.Face)
+		i := len(out) - 1
+		for i >= 0 && out[i].Face != t.Face {
+			if a, _ := axisOf(out[i].Face); a != axis {
+				break
+			}
+			i--
+		}
+		if i >= 0 && out[i].Face == t.Face {
+			out[i].Amount = (out[i].Amount + t.Amount) % 4
+			if out[i].Amount == 0 {
+				out = append(out[:i], out[i+1:]...)
+			}
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// TurnsString writes turns back out: r /u d2
+func TurnsString(turns []Turn) string {
+	names := make([]string, 0, len(turns))
+	for _, t := range turns {
+		switch t.Amount {
+		case 1:
+			names = append(names, t.Face)
+		case 2:
+			names = append(names, t.Face+"2")
+		case 3:
+			names = append(names, "/"+t.Face)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// sliceTurns counts a run of turns that are all on one axis in the slice
+// turn metric. When there are whole cube turns, the three layers are
+// turned by some amount each, and the cheapest way to write that is used.
+func sliceTurns(run []Turn) int {
+	layers := [3]int{}
+	rotated := false
+	n := 0
+	for _, t := range run {
+		axis, dir := axisOf(t.Face)
+		a := dir * t.Amount
+		switch {
+		case strings.ToUpper(t.Face) == t.Face:
+			rotated = true
+			for i := range layers {
+				layers[i] += a
+			}
+		case t.Face == axis:
+			layers[0] += a
+			n++
+		default:
+			layers[2] += a
+			n++
+		}
+	}
+	if !rotated {
+		return n
+	}
+	// rotate back by r, and count the layers that still turn.
+	// a rotation is free, so it only breaks a tie, and stm is never more
+	// than the face turns, which is the cost without rotating back.
+	best, bestScore := 0, -1
+	for r := 0; r < 4; r++ {
+		cost := 0
+		for _, l := range layers {
+			if ((l-r)%4+4)%4 != 0 {
+				cost++
+			}
+		}
+		score := 2 * cost
+		if r != 0 {
+			score++
+		}
+		if bestScore < 0 || score < bestScore {
+			best, bestScore = cost, score
+		}
+	}
+	return best
+}
+
+// Measure counts turns in every metric
+func Measure(turns []Turn) Metrics {
+	m := Metrics{}
+	run := make([]Turn, 0)
+	runAxis := ""
+	for _, t := range turns {
+		if t.Amount == 0 {
+			continue
+		}
+		m.ETM++
+		if strings.ToUpper(t.Face) == t.Face {
+			m.Rotations++
+		} else {
+			m.HTM++
+			m.QTM++
+			if t.Amount == 2 {
+				m.QTM++
+			}
+		}
+		axis, _ := axisOf(t.Face)
+		if axis != runAxis {
+			m.STM += sliceTurns(run)
+			run = run[:0]
+			runAxis = axis
+		}
+		run = append(run, t)
+	}
+	m.STM += sliceTurns(run)
+	return m
+}
+
+// MeasureMoves counts a flattened move list as it is, and after simplifying it
+func MeasureMoves(flattened string) (Metrics, Metrics, string, error) {
+	turns, err := ParseTurns(flattened)
+	if err != nil {
+		return Metrics{}, Metrics{}, "", err
+	}
+	simple := SimplifyTurns(turns)
+	return Measure(turns), Measure(simple), TurnsString(simple), nil
+}
+
+// Measure executes a command on a scratch cube, to count its turns
+func (cube *Cube) Measure(cmd string) (Metrics, Metrics, string, error) {
+	parsed, err := cube.Parse(cmd)
+	if err != nil {
+		return Metrics{}, Metrics{}, "", err
+	}
+	flattened, err := NewCube().Execute(parsed, 0, 0, 0, 0, 0)
+	if err != nil {
+		return Metrics{}, Metrics{}, "", err
+	}
+	return MeasureMoves(flattened)
+}
@@ -0,0 +1,281 @@
+package cube
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+  Searching needs something much faster than turning a map of stickers.
+  Every sticker location gets an index, and a move becomes a permutation
+  of those indices.
+
+  Permutations are never written out by hand. They are computed by turning
+  a cube whose stickers are labeled with their own location names, so they
+  always agree with Turn1. The labels still start with the face name, so
+  Turn1 finds 9 stickers of every color and is happy with it.
+*/
+
+// StickerCount is the number of sticker locations, centers included
+const StickerCount = 54
+
+// Perm is a permutation of sticker locations, indexed like StickerKeys.
+// p[i] is the location whose sticker ends up at location i.
+//
+// A Perm is also a state: when applied to a solved cube, p[i] tells which
+// original sticker now sits at location i.
+type Perm [StickerCount]uint8
+
+// StickerKeys are the sticker location names, in index order. They are set
+// before any init runs, so that the state of a cube can be taken in one.
+var StickerKeys = sortedStickerKeys()
+
+var stickerIndex = indexStickers(StickerKeys)
+
+func sortedStickerKeys() []string {
+	keys := make([]string, 0, StickerCount)
+	for k := range NewCube().Stickers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func indexStickers(keys []string) map[string]int {
+	index := make(map[string]int)
+	for i, k := range keys {
+		index[k] = i
+	}
+	return index
+}
+
+// IdentityPerm leaves every sticker in place
+func IdentityPerm() Perm {
+	var p Perm
+	for i := range p {
+		p[i] = uint8(i)
+	}
+	return p
+}
+
+// Then is p followed by q
+func (p Perm) Then(q Perm) Perm {
+	var r Perm
+	for i := range r {
+		r[i] = p[q[i]]
+	}
+	return r
+}
+
+// Inverse undoes p
+func (p Perm) Inverse() Perm {
+	var r Perm
+	for i := range p {
+		r[p[i]] = uint8(i)
+	}
+	return r
+}
+
+func (p Perm) IsIdentity() bool {
+	return p == IdentityPerm()
+}
+
+// Moved counts the sticker locations that p changes
+func (p Perm) Moved() int {
+	n := 0
+	for i := range p {
+		if int(p[i]) != i {
+			n++
+		}
+	}
+	return n
+}
+
+// Order is the number of times p must be repeated to get back to solved
+func (p Perm) Order() int {
+	gcd := func(a, b int) int {
+		for b != 0 {
+			a, b = b, a%b
+		}
+		return a
+	}
+	order := 1
+	seen := make([]bool, len(p))
+	for i := range p {
+		if seen[i] {
+			continue
+		}
+		n := 0
+		for j := i; !seen[j]; j = int(p[j]) {
+			seen[j] = true
+			n++
+		}
+		order = order / gcd(order, n) * n
+	}
+	return order
+}
+
+// labeledCube is a solved cube where every sticker value is its own location name
+func labeledCube() *Cube {
+	cube := NewCube()
+	for k := range cube.Stickers {
+		cube.Stickers[k] = k
+	}
+	return cube
+}
+
+// labeledPerm reads the permutation back out of a labeled cube
+func labeledPerm(cube *Cube) Perm {
+	var p Perm
+	for i, k := range StickerKeys {
+		p[i] = uint8(stickerIndex[cube.Stickers[k]])
+	}
+	return p
+}
+
+// PermOf executes a parsed command on a scratch cube, and returns what it does.
+func (cube *Cube) PermOf(node Node) (Perm, error) {
+	scratch := labeledCube()
+	_, err := scratch.Execute(node, 0, 0, 0, 0, 0)
+	if err != nil {
+		return Perm{}, err
+	}
+	return labeledPerm(scratch), nil
+}
+
+// pieceOf names the piece that a sticker location belongs to,
+// starting from that sticker, and going clockwise for corners.
+//
+//	uf -> uf,fu   urf -> urf,rfu,fur
+func pieceOf(k string) []string {
+	switch len(k) {
+	case 2:
+		return []string{k, k[1:] + k[:1]}
+	case 3:
+		return []string{k, k[1:] + k[:1], k[2:] + k[:2]}
+	}
+	return []string{k}
+}
+
+// Tracking works out which original sticker sits at every location.
+// Every piece has a distinct set of colors, and turns never change the
+// winding of a corner, so reading the colors around a piece is its name.
+func (cube *Cube) Tracking() (Perm, error) {
+	var p Perm
+	for i, k := range StickerKeys {
+		name := ""
+		for _, at := range pieceOf(k) {
+			name += cube.Stickers[at]
+		}
+		j, ok := stickerIndex[name]
+		if !ok {
+			return Perm{}, fmt.Errorf("no piece has colors %s, seen at %s", name, k)
+		}
+		p[i] = uint8(j)
+	}
+	return p, nil
+}
+
+// SetTracking colors the cube so that it is in state p
+func (cube *Cube) SetTracking(p Perm) {
+	for i, k := range StickerKeys {
+		cube.Stickers[k] = StickerKeys[p[i]][:1]
+	}
+}
+
+// move is a named permutation that a search may use
+type move struct {
+	Name string
+	Perm Perm
+	// Face is the face (or generator number) being turned, so that searches
+	// can skip turning the same thing twice in a row
+	Face int
+	// Axis is the same for faces that commute, like u and d
+	Axis int
+	// Turn is 1, 2 or -1 for face turns
+	Turn int
+	// Item is the move as something that Print understands
+	Item Node
+}
+
+// Node is the move as one item of an expression
+func (m move) Node() Node {
+	return m.Item
+}
+
+var faceMoveCache []move
+
+// faceMoves are the quarter and half turns of all six faces, in the order
+// of cube.Faces: u u2 /u r r2 /r ...
+func faceMoves() []move {
+	return faceMoveCache
+}
+
+// makeFaceMoves fills in faceMoves, once, before any search can use them
+func makeFaceMoves() error {
+	cube := NewCube()
+	for fi, f := range cube.Faces {
+		// both faces on an axis use the lower face number
+		axis := fi
+		for oi, o := range cube.Faces {
+			if o == cube.Opposite[f] && oi < fi {
+				axis = oi
+			}
+		}
+		for _, turn := range []int{1, 2, -1} {
+			scratch := labeledCube()
+			if err := scratch.Turn(f, turn); err != nil {
+				return err
+			}
+			item := Node{Face: f, Repeat: 1}
+			switch turn {
+			case 2:
+				item.Repeat = 2
+			case -1:
+				item.Negate = true
+			}
+			faceMoveCache = append(faceMoveCache, move{
+				Name: item.Print(),
+				Perm: labeledPerm(scratch),
+				Face: fi,
+				Axis: axis,
+				Turn: turn,
+				Item: item,
+			})
+		}
+	}
+	return nil
+}
+
+// movesNode groups a list of moves, without parens for a single move
+func movesNode(moves []move) Node {
+	if len(moves) == 1 {
+		return moves[0].Node()
+	}
+	arr := make([]Node, 0, len(moves))
+	for _, m := range moves {
+		arr = append(arr, m.Node())
+	}
+	return Node{Arr: arr, Repeat: 1}
+}
+
+// canFollow prunes sequences that could be written shorter: turning the
+// same face twice, or turning opposite faces in both orders.
+func canFollow(prev []move, m move) bool {
+	if len(prev) == 0 {
+		return true
+	}
+	last := prev[len(prev)-1]
+	if last.Face == m.Face {
+		return false
+	}
+	if last.Axis == m.Axis {
+		if last.Face > m.Face {
+			return false
+		}
+		if len(prev) > 1 && prev[len(prev)-2].Face == m.Face {
+			return false
+		}
+	}
+	return true
+}
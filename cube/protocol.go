@@ -0,0 +1,124 @@
+package cube
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+/*
+  Requests and responses are for programs, like something that asks an AI
+  to solve cubes. -json reads a request per line, and writes a response
+  per line:
+
+    {"id": 1, "op": "execute", "expr": "[fr]3"}
+    {"id": 1, "ok": true, "op": "execute", "expr": "[fr]3", "text": "([f r]3)", "node": {...},
+     "moves": ["f", "r", "/f", ...], "stickers": {...}, "facelets": "...",
+     "solved": false, "undo": 1}
+
+  The ops are:
+
+    parse    parse expr, without running it
+    execute  parse expr and run it
+    state    just the state
+    undo     put back the state before the last execute
+    reset    a new cube
+    check    whether the cube is solved, or just the stickers of mask,
+             which is mask names and stickers like the solve command takes
+
+  Every response has the state of the cube after the request: stickers by
+  location, the facelets like -print state, whether it is solved, and how
+  many executes undo can put back. When something fails, ok is false and
+  error says which kind of thing failed (request, parse, execute, undo or
+  mask) and why. The id of a request is sent back as it came.
+*/
+
+type Request struct {
+	ID   json.RawMessage `json:"id,omitempty"`
+	Op   string          `json:"op"`
+	Expr string          `json:"expr,omitempty"`
+	Mask string          `json:"mask,omitempty"`
+}
+
+type ResponseError struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+type Response struct {
+	ID       json.RawMessage   `json:"id,omitempty"`
+	OK       bool              `json:"ok"`
+	Op       string            `json:"op,omitempty"`
+	Expr     string            `json:"expr,omitempty"`
+	Text     string            `json:"text,omitempty"`
+	Node     *Tree             `json:"node,omitempty"`
+	Moves    []string          `json:"moves,omitempty"`
+	Stickers map[string]string `json:"stickers"`
+	Facelets string            `json:"facelets"`
+	Solved   bool              `json:"solved"`
+	Undo     int               `json:"undo"`
+	Error    *ResponseError    `json:"error,omitempty"`
+}
+
+// Respond does a request to the cube, with masks for check. It gives back
+// the cube, since reset makes a new one.
+func (cube *Cube) Respond(req Request, masks map[string]Mask) (*Cube, Response) {
+	resp := Response{ID: req.ID, Op: req.Op, Expr: req.Expr}
+	fail := func(kind string, err error) {
+		resp.Error = &ResponseError{Kind: kind, Message: err.Error()}
+	}
+	var mask *Mask
+	switch req.Op {
+	case "parse", "execute":
+		node, err := cube.Parse(req.Expr)
+		if err != nil {
+			fail("parse", err)
+			break
+		}
+		tree := TreeOf(node)
+		resp.Text, resp.Node = node.Print(), &tree
+		if req.Op == "parse" {
+			break
+		}
+		moves, err := cube.ExecuteCommand(node)
+		if err != nil {
+			// put back what it did before it failed
+			cube.Pop()
+			fail("execute", err)
+			break
+		}
+		resp.Moves = strings.Fields(moves)
+	case "state":
+	case "undo":
+		if !cube.Pop() {
+			fail("undo", fmt.Errorf("nothing to undo"))
+		}
+	case "reset":
+		cube = NewCube()
+	case "check":
+		if strings.TrimSpace(req.Mask) == "" {
+			break
+		}
+		m, err := MaskOf(req.Mask, strings.Fields(req.Mask), masks)
+		if err != nil {
+			fail("mask", err)
+			break
+		}
+		mask = &m
+	default:
+		fail("request", fmt.Errorf("unknown op %q. ops: parse execute state undo reset check", req.Op))
+	}
+	resp.OK = resp.Error == nil
+	return cube, cube.WithState(resp, mask)
+}
+
+// WithState fills in the state of the cube, solved for the mask if there is one
+func (cube *Cube) WithState(resp Response, mask *Mask) Response {
+	resp.Stickers, resp.Facelets = CopyStickers(cube.Stickers), cube.Facelets()
+	resp.Solved, resp.Undo = cube.Solved(), len(cube.History)
+	if mask != nil {
+		state, err := cube.Tracking()
+		resp.Solved = err == nil && mask.Solved(state)
+	}
+	return resp
+}
@@ -0,0 +1,154 @@
+package cube
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+  Searches work on Perm states, and turn faces with the moves from
+  faceMoves. They print what they find in the move language, so the
+  answer can be typed back in, or executed to check it.
+*/
+
+// genMoves are the moves allowed by -gen, or all face moves.
+// -gen ru only turns r and u, and -gen [fr],u uses expressions as moves.
+func genMoves(gen string) ([]move, error) {
+	all := faceMoves()
+	if gen == "" {
+		return all, nil
+	}
+	if !onlyFaces(gen) || strings.ToLower(gen) != gen {
+		exprs, err := Generators(gen)
+		if err != nil {
+			return nil, err
+		}
+		return expressionMoves(exprs)
+	}
+	faces := NewCube().Faces
+	moves := make([]move, 0)
+	for _, f := range gen {
+		fi := -1
+		for i := range faces {
+			if faces[i] == string(f) {
+				fi = i
+			}
+		}
+		if fi < 0 {
+			return nil, fmt.Errorf("-gen should only name faces like ru, not %c", f)
+		}
+		for _, m := range all {
+			if m.Face == fi {
+				moves = append(moves, m)
+			}
+		}
+	}
+	return moves, nil
+}
+
+// movesString writes moves the way Execute flattens them
+func movesString(moves []move) string {
+	names := make([]string, 0, len(moves))
+	for _, m := range moves {
+		names = append(names, m.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+type searchStep struct {
+	From Perm
+	Move int
+}
+
+// bridge finds a shortest list of moves that takes state from to state to,
+// searching from both ends at once, and giving up past depth moves.
+func bridge(from Perm, to Perm, moves []move, depth int) ([]move, bool) {
+	if from == to {
+		return nil, true
+	}
+	inverses := make([]Perm, len(moves))
+	for i := range moves {
+		inverses[i] = moves[i].Perm.Inverse()
+	}
+
+	// fwd has how every state was reached from the start,
+	// and bwd has how every state gets to the goal
+	fwd := map[Perm]searchStep{from: {Move: -1}}
+	bwd := map[Perm]searchStep{to: {Move: -1}}
+	fwdEdge := []Perm{from}
+	bwdEdge := []Perm{to}
+
+	path := func(meet Perm) []move {
+		found := make([]move, 0)
+		for at := meet; fwd[at].Move >= 0; at = fwd[at].From {
+			found = append([]move{moves[fwd[at].Move]}, found...)
+		}
+		for at := meet; bwd[at].Move >= 0; at = bwd[at].From {
+			found = append(found, moves[bwd[at].Move])
+		}
+		return found
+	}
+
+	for d := 0; d < depth; d++ {
+		// grow the smaller side
+		forward := len(fwdEdge) <= len(bwdEdge)
+		seen, other, edge := fwd, bwd, fwdEdge
+		if !forward {
+			seen, other, edge = bwd, fwd, bwdEdge
+		}
+		next := make([]Perm, 0)
+		for _, s := range edge {
+			for i := range moves {
+				var t Perm
+				if forward {
+					t = s.Then(moves[i].Perm)
+				} else {
+					t = s.Then(inverses[i])
+				}
+				if _, ok := seen[t]; ok {
+					continue
+				}
+				seen[t] = searchStep{From: s, Move: i}
+				if _, ok := other[t]; ok {
+					return path(t), true
+				}
+				next = append(next, t)
+			}
+		}
+		if forward {
+			fwdEdge = next
+		} else {
+			bwdEdge = next
+		}
+		if len(next) == 0 {
+			break
+		}
+	}
+	return nil, false
+}
+
+// Bridge finds the shortest moves that take this cube to look like the other cube.
+func (cube *Cube) Bridge(other *Cube, gen string, depth int) (string, error) {
+	for _, f := range cube.Faces {
+		if cube.Stickers[f] != other.Stickers[f] {
+			return "", fmt.Errorf("the cubes are not held the same way. centers differ at %s", f)
+		}
+	}
+	from, err := cube.Tracking()
+	if err != nil {
+		return "", err
+	}
+	to, err := other.Tracking()
+	if err != nil {
+		return "", err
+	}
+	moves, err := genMoves(gen)
+	if err != nil {
+		return "", err
+	}
+	found, ok := bridge(from, to, moves, depth)
+	if !ok {
+		return "", fmt.Errorf("no bridge within %d moves", depth)
+	}
+	return movesString(found), nil
+}
@@ -0,0 +1,479 @@
+package cube
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/gif"
+	"io"
+	"strings"
+)
+
+/*
+It seems a little strange to do this instead of standard Go test, but
+I will include integration tests if I am to provide internal parameters,
+for example to hook up to OpenAI and ask it to solve cubes.
+
+But any parameters not compiled in would be cause to do a SelfTest.
+It writes what it checks to w, and stops at the first thing that fails.
+*/
+func SelfTest(w io.Writer) error {
+	fmt.Fprintf(w, "running post test\n")
+	checkInterpretation := func(s string, theCube *Cube) (Node, error) {
+		fmt.Fprintf(w, "checkInterpretation: %s\n", s)
+		expect := "(" + s + ")"
+		parsed, err := theCube.Parse(s)
+		if err != nil {
+			return parsed, fmt.Errorf("parse error on example %s: %s", s, err)
+		}
+		got := parsed.Print()
+		got = sameMeaning(got)
+		expect = sameMeaning(expect)
+		if expect != got {
+			return parsed, fmt.Errorf("expect interpretation of %s to be: %s", expect, got)
+		}
+		return parsed, nil
+	}
+
+	checkExecution := func(parsed Node, theCube *Cube) error {
+		fmt.Fprintf(w, "checkExecution: %s\n", parsed.Print())
+		if _, err := theCube.ExecuteCommand(parsed); err != nil {
+			return fmt.Errorf("execute error on example %s: %s", parsed.Print(), err)
+		}
+		return nil
+	}
+
+	checkInvertability := func(s string) error {
+		fmt.Fprintf(w, "checkInvertability: %s\n", s)
+		if len(s) < 3 {
+			return nil
+		}
+		sNot := s
+		if s[0] == '/' && (s[1] == '(' || s[1] == '[') {
+			sNot = s[1:]
+		} else {
+			sNot = "/(" + s + ")"
+		}
+		c1 := NewCube()
+		node, err := c1.Parse(s)
+		if err != nil {
+			return fmt.Errorf("parse error on example invertability chech %s: %s", s, err)
+		}
+		ex1, err := c1.ExecuteCommand(node)
+		if err != nil {
+			return fmt.Errorf("execute error on example invertability chech %s: %s", s, err)
+		}
+		node, err = c1.Parse(sNot)
+		if err != nil {
+			return fmt.Errorf("parse error on example invertability chech %s: %s", sNot, err)
+		}
+		ex2, err := c1.ExecuteCommand(node)
+		if err != nil {
+			return fmt.Errorf("execute error on example invertability chech %s: %s", sNot, err)
+		}
+		for k, v := range c1.Stickers {
+			if string(k[0]) != v {
+				return fmt.Errorf("inverse check: %s not inverted by %s.\nfwd: %s\nrev: %s", s, sNot, ex1, ex2)
+			}
+		}
+		return nil
+	}
+
+	// check runs s on a new cube, after checking that it is undone by its negation
+	check := func(s string) (*Cube, error) {
+		if err := checkInvertability(s); err != nil {
+			return nil, err
+		}
+		theCube := NewCube()
+		parsed, err := checkInterpretation(s, theCube)
+		if err != nil {
+			return nil, err
+		}
+		return theCube, checkExecution(parsed, theCube)
+	}
+
+	for i := range EqTest {
+		// check the INTERPRETATION after a parse
+		s := StrippedComment(EqTest[i][0])
+		cube1, err := check(s)
+		if err != nil {
+			return err
+		}
+
+		// compare next string cubes to current cube state.
+		// stickers should be the same to pass the test.
+		for j := 1; j < len(EqTest[i]); j++ {
+			s2 := StrippedComment(EqTest[i][j])
+			cube2, err := check(s2)
+			if err != nil {
+				return err
+			}
+
+			// compare stickers to make sure they are equivalent as a parse
+			to := s2
+			if to == "" {
+				to = "()"
+			}
+			fmt.Fprintf(w, "checkEquality: %s == %s\n", s, to)
+			for k := range cube1.Stickers {
+				got := cube1.Stickers[k]
+				expected := cube2.Stickers[k]
+				if got != expected {
+					return fmt.Errorf("stickers should be the same in %s: sticker %s got %s instead of %s", s, k, got, expected)
+				}
+			}
+		}
+	}
+	// find3 verifies what it finds, so any error is a failure
+	for _, cycle := range [][]string{{"uf", "ur", "ub"}, {"urf", "ubr", "ulb"}} {
+		fmt.Fprintf(w, "checkFind3: %s\n", strings.Join(cycle, " "))
+		if _, _, err := NewCube().Find3(cycle[0], cycle[1], cycle[2]); err != nil {
+			return fmt.Errorf("find3 error on %s: %s", strings.Join(cycle, " "), err)
+		}
+	}
+	// solving a mask after a scramble should leave it solved
+	masks, err := Presets()
+	if err != nil {
+		return fmt.Errorf("masks error: %s", err)
+	}
+	for _, name := range []string{"cross", "eo"} {
+		fmt.Fprintf(w, "checkSolve: %s\n", name)
+		scrambled := NewCube()
+		if err := scrambled.Apply("(fdrfdbl)5"); err != nil {
+			return fmt.Errorf("scramble error: %s", err)
+		}
+		found, err := scrambled.Solve(masks[name], "", 10)
+		if err == nil {
+			err = scrambled.Apply(found)
+		}
+		if err != nil {
+			return fmt.Errorf("solve error on %s: %s", name, err)
+		}
+		state, _ := scrambled.Tracking()
+		if !masks[name].Solved(state) {
+			return fmt.Errorf("solve %s did not solve it with %s", name, found)
+		}
+	}
+	// metrics count the moves as they are, and after merging turns of a face
+	for _, m := range []struct {
+		expr       string
+		raw        Metrics
+		simple     Metrics
+		simplified string
+	}{
+		{"[[fr]3 u]", Metrics{26, 26, 26, 26, 0}, Metrics{26, 26, 26, 26, 0}, ""},
+		{"R u2 /u d", Metrics{3, 4, 3, 4, 1}, Metrics{2, 2, 2, 3, 1}, "R u d"},
+		{"R /r l", Metrics{2, 2, 1, 3, 1}, Metrics{2, 2, 1, 3, 1}, "R /r l"},
+		{"R /r", Metrics{1, 1, 1, 2, 1}, Metrics{1, 1, 1, 2, 1}, "R /r"},
+		{"R L /r", Metrics{1, 1, 1, 3, 2}, Metrics{1, 1, 1, 3, 2}, ""},
+	} {
+		fmt.Fprintf(w, "checkMetrics: %s\n", m.expr)
+		raw, simple, simplified, err := NewCube().Measure(m.expr)
+		if err != nil {
+			return fmt.Errorf("metrics error on %s: %s", m.expr, err)
+		}
+		if raw != m.raw || simple != m.simple || m.simplified != "" && simplified != m.simplified {
+			return fmt.Errorf("metrics of %s should be %s / %s (%s), not %s / %s (%s)",
+				m.expr, m.raw, m.simple, m.simplified, raw, simple, simplified)
+		}
+	}
+	// labels name locations, or the solved location of the sticker there now
+	fmt.Fprintf(w, "checkLabels: keys and pieces after r\n")
+	labeledStyle := NewStyle()
+	afterR := NewCube()
+	if err := afterR.Apply("r"); err != nil {
+		return fmt.Errorf("labels error: %s", err)
+	}
+	for _, l := range []struct{ mode, at, label string }{{"keys", "fur", "fur"}, {"pieces", "fur", "dfr"}, {"pieces", "u", "u"}} {
+		labeledStyle.Labels = l.mode
+		if got := labeledStyle.stickerLabels(afterR)[l.at]; got != l.label {
+			return fmt.Errorf("labels %s after r should show %s at %s, not %s", l.mode, l.label, l.at, got)
+		}
+	}
+	broken := NewCube()
+	broken.Stickers["fur"] = "u"
+	if got := labeledStyle.stickerLabels(broken)["fur"]; got != "?" {
+		return fmt.Errorf("labels pieces should show ? for colors that make no piece, not %s", got)
+	}
+	// Draw puts as many cubes side by side as fit, and the rest under them
+	plain := NewStyle()
+	plain.Ansi = false
+	_, netWidth, err := plain.NetLines(NewCube())
+	if err != nil {
+		return fmt.Errorf("draw error: %s", err)
+	}
+	captions := []string{"cube-a", "cube-b", "cube-c"}
+	for _, d := range []struct{ columns, rows int }{{3*netWidth + 2*blockSep, 1}, {2*netWidth + blockSep, 2}, {netWidth, 3}} {
+		fmt.Fprintf(w, "checkDraw: 3 cubes in %d columns\n", d.columns)
+		var b strings.Builder
+		if err := plain.Draw(&b, []*Cube{NewCube(), NewCube(), NewCube()}, captions, d.columns); err != nil {
+			return fmt.Errorf("draw error: %s", err)
+		}
+		rows := 0
+		for _, line := range strings.Split(b.String(), "\n") {
+			if strings.Contains(line, "cube-") {
+				rows++
+			}
+			if len(strings.TrimRight(line, " ")) > d.columns {
+				return fmt.Errorf("draw in %d columns wrote a line %d wide", d.columns, len(strings.TrimRight(line, " ")))
+			}
+		}
+		if rows != d.rows {
+			return fmt.Errorf("draw of 3 cubes in %d columns should take %d rows, not %d", d.columns, d.rows, rows)
+		}
+	}
+	// fewer captions than cubes leaves the rest blank, and a long one is cut
+	fmt.Fprintf(w, "checkDraw: one long caption for 2 cubes\n")
+	var twoCubes strings.Builder
+	long := strings.Repeat("cube-", netWidth)
+	if err := plain.Draw(&twoCubes, []*Cube{NewCube(), NewCube()}, []string{long}, 2*netWidth+blockSep); err != nil {
+		return fmt.Errorf("draw error: %s", err)
+	}
+	if !strings.Contains(twoCubes.String(), long[:netWidth]+strings.Repeat(" ", blockSep+netWidth)) {
+		return fmt.Errorf("draw should cut a long caption to %d, and leave a missing one blank", netWidth)
+	}
+	// the svg net is xml, with a sticker for every place in the net, colored
+	// like the sticker at the location in its title
+	fmt.Fprintf(w, "checkSVG: both cubes after r, with a caption\n")
+	var picture struct {
+		Rects []struct {
+			Fill  string `xml:"fill,attr"`
+			Title string `xml:"title"`
+		} `xml:"rect"`
+		Text string `xml:"text"`
+	}
+	var svg strings.Builder
+	if err := NewStyle().DrawSVG(&svg, []*Cube{afterR, NewCube()}, "r <x 1>"); err != nil {
+		return fmt.Errorf("svg error: %s", err)
+	}
+	if err := xml.Unmarshal([]byte(svg.String()), &picture); err != nil {
+		return fmt.Errorf("svg is not xml: %s", err)
+	}
+	netStickers := 0
+	for _, row := range NetRows {
+		for _, k := range row {
+			if k != "" {
+				netStickers++
+			}
+		}
+	}
+	if len(picture.Rects) != 1+2*netStickers || picture.Text != "r <x 1>" {
+		return fmt.Errorf("svg of 2 cubes should have %d stickers and the caption, not %d and %q", 2*netStickers, len(picture.Rects)-1, picture.Text)
+	}
+	for i, rect := range picture.Rects[1 : 1+netStickers] {
+		if want := NewStyle().FaceRGB[afterR.Stickers[rect.Title]]; rect.Fill != want {
+			return fmt.Errorf("svg sticker %d at %s should be %s, not %s", i, rect.Title, want, rect.Fill)
+		}
+	}
+	// the front view of a new cube is only u f r, and the opposite one d l b
+	fmt.Fprintf(w, "checkIso: the two views of a new cube, as text and svg\n")
+	plainIso := NewStyle()
+	plainIso.Ansi = false
+	isoText, isoW, err := plainIso.IsoLines(NewCube())
+	if err != nil {
+		return fmt.Errorf("iso error: %s", err)
+	}
+	for _, line := range isoText[:isoHeight] {
+		if len(line) != isoW || strings.Trim(line[:isoW/2], " ufr") != "" || strings.Trim(line[isoW/2:], " dlb") != "" {
+			return fmt.Errorf("iso views should be %d wide, u f r then d l b, not %q", isoW, line)
+		}
+	}
+	var isoPicture struct {
+		Polygons []struct {
+			Fill string `xml:"fill,attr"`
+		} `xml:"polygon"`
+	}
+	var isoSVG strings.Builder
+	if err := NewStyle().IsoSVG(&isoSVG, []*Cube{NewCube()}, ""); err != nil {
+		return fmt.Errorf("iso error: %s", err)
+	}
+	if err := xml.Unmarshal([]byte(isoSVG.String()), &isoPicture); err != nil || len(isoPicture.Polygons) != StickerCount {
+		return fmt.Errorf("iso svg should be %d stickers, not %d (%v)", StickerCount, len(isoPicture.Polygons), err)
+	}
+	faceOf := make(map[string]string)
+	for f, rgb := range NewStyle().FaceRGB {
+		faceOf[rgb] = f
+	}
+	for i, p := range isoPicture.Polygons {
+		seen := "ufr"
+		if i >= StickerCount/2 {
+			seen = "dlb"
+		}
+		if !strings.ContainsAny(seen, faceOf[p.Fill]) {
+			return fmt.Errorf("iso svg sticker %d should be one of %s, not %s", i, seen, p.Fill)
+		}
+	}
+	// a gif has the cube before, and a frame per quarter turn after it
+	fmt.Fprintf(w, "checkGIF: frames of [f u2], and the layer of r\n")
+	fu2, err := NewCube().Parse("[f u2]")
+	if err != nil {
+		return fmt.Errorf("gif error: %s", err)
+	}
+	var animation bytes.Buffer
+	if err := NewStyle().GIF(&animation, NewCube(), fu2); err != nil {
+		return fmt.Errorf("gif error: %s", err)
+	}
+	if decoded, err := gif.DecodeAll(&animation); err != nil || len(decoded.Image) != 7 {
+		return fmt.Errorf("gif of [f u2] should be 7 frames for f u u /f /u /u (%v)", err)
+	}
+	if layer, err := layerOf("r"); err != nil || len(layer) != 21 || !layer["r"] || !layer["urf"] || layer["ul"] {
+		return fmt.Errorf("the layer of r should be its 9 stickers and the 12 around them, not %d (%v)", len(layer), err)
+	}
+	tooLongForGIF, _ := NewCube().Parse("[[fr]3 u]20")
+	if err := NewStyle().GIF(io.Discard, NewCube(), tooLongForGIF); err == nil {
+		return fmt.Errorf("a gif of [[fr]3 u]20 is more than %d frames, and should be refused", gifFrameMax)
+	}
+	// a colors file comes back the way it was written, and b is orange
+	// wherever the terminal has orange
+	fmt.Fprintf(w, "checkColors: a custom colors file, and b in every mode\n")
+	colorsFile := "scheme: custom\nmode: truecolor\noverlay: letters\nu: #ffffff\nr: #0046ad\nf: #b71234\nd: #ffd500\nl: #009b48\nb: #ff8000\n"
+	colored := NewStyle()
+	config, custom, err := colored.ParseColors("scheme: japanese -- replaced by the faces\n" + colorsFile)
+	if err == nil {
+		err = colored.SetColors(config, custom)
+	}
+	if err != nil || colored.ColorsText() != colorsFile {
+		return fmt.Errorf("colors file should be read back as it was written, not %q (%v)", colored.ColorsText(), err)
+	}
+	for _, m := range [][2]string{{"truecolor", "48;2;255;128;0;30mb"}, {"256", "48;5;214;30mb"}, {"16", "45;37mb"}} {
+		if err := colored.UseColorWords([]string{m[0]}); err != nil {
+			return fmt.Errorf("colors error: %s", err)
+		}
+		if block := colored.StickerBlock("b"); !strings.Contains(block, m[1]) {
+			return fmt.Errorf("b in mode %s should be drawn with %q, not %q", m[0], m[1], block)
+		}
+	}
+	if err := colored.UseColorWords([]string{"pink"}); err == nil || colored.Colors.Mode != "16" {
+		return fmt.Errorf("pink is not a color word, and should change nothing")
+	}
+	// changed stickers are the ones whose color is not what it was, so on a
+	// new cube r only marks the 12 around the face, and R leaves r and l
+	for _, h := range []struct {
+		expr    string
+		changed int
+	}{{"r", 12}, {"u d", 24}, {"R", 36}} {
+		fmt.Fprintf(w, "checkHighlight: %s\n", h.expr)
+		marked := NewCube()
+		if err := marked.Apply(h.expr); err != nil {
+			return fmt.Errorf("highlight error: %s", err)
+		}
+		if got := len(marked.Changed()); got != h.changed || len(marked.Diff(NewCube())) != h.changed {
+			return fmt.Errorf("%s should change %d stickers, not %d", h.expr, h.changed, got)
+		}
+		marked.Pop()
+		if len(marked.Changed()) != 0 {
+			return fmt.Errorf("after undo of %s, nothing should be marked", h.expr)
+		}
+	}
+	if got := plain.DiffString(afterR, NewCube()); !strings.HasSuffix(got, "12 stickers on 8 pieces differ\n") {
+		return fmt.Errorf("diff of r should be 12 stickers on 8 pieces, not %q", got)
+	}
+	// a display mask of f2l greys out the whole u layer, and keeps the color
+	// underneath, without changing the cube
+	fmt.Fprintf(w, "checkMask: f2l shown on r, and masks made of masks\n")
+	drill := NewStyle()
+	f2l := masks["f2l"]
+	drill.Mask = &f2l
+	shown := drill.Shown(afterR)
+	greyed := 0
+	for _, v := range shown.Stickers {
+		if isMasked(v) {
+			greyed++
+		}
+	}
+	if greyed != StickerCount-len(f2l.Class) || shown.Stickers["fur"] != afterR.Stickers["fur"]+maskedSuffix || isMasked(afterR.Stickers["fur"]) {
+		return fmt.Errorf("f2l should grey out %d stickers of a copy, with fur as %s-, not %d and %s", StickerCount-len(f2l.Class), afterR.Stickers["fur"], greyed, shown.Stickers["fur"])
+	}
+	for _, k := range masks["lastlayer"].Keys() {
+		if !isMasked(shown.Stickers[k]) {
+			return fmt.Errorf("f2l should grey out %s in the u layer", k)
+		}
+	}
+	drills := make(map[string]Mask)
+	for name, m := range masks {
+		drills[name] = m
+	}
+	if err := ParseMasks("myslot: cross fl lf dlf lfd fdl", drills); err != nil || len(drills["myslot"].Class) != len(masks["cross"].Class)+5 {
+		return fmt.Errorf("myslot should be the cross and 5 stickers, not %d (%v)", len(drills["myslot"].Class), err)
+	}
+	if err := ParseMasks("typo: cross dx", drills); err == nil {
+		return fmt.Errorf("a mask with dx in it should be an error")
+	}
+	// tree shows what a node is, and trace the order its parts really run
+	// in, which is different for a negated conjugate
+	fmt.Fprintf(w, "checkTree: /{f r}2\n")
+	conjugate, err := NewCube().Parse("/{f r}2")
+	if err != nil {
+		return fmt.Errorf("tree error: %s", err)
+	}
+	if tree := TreeOf(conjugate).Children; len(tree) != 1 || tree[0].Kind != "conjugate" || !tree[0].Negate || tree[0].Repeat != 2 || len(tree[0].Children) != 2 {
+		return fmt.Errorf("/{f r}2 should be a negated conjugate, repeated 2 times, of 2 turns: %v", tree)
+	}
+	for _, t := range []struct{ expr, leaves string }{
+		{"{f r}", "f:0:f r:0:r f:1:/f"},
+		{"/{f r}", "f:2:f r:1:/r f:1:/f"},
+		{"(x r u)", "r:0:x1:/l u:0:x1:/u"},
+	} {
+		fmt.Fprintf(w, "checkTrace: %s\n", t.expr)
+		node, err := NewCube().Parse(t.expr)
+		if err != nil {
+			return fmt.Errorf("trace error: %s", err)
+		}
+		visits, err := NewCube().TraceVisits(node)
+		if err != nil {
+			return fmt.Errorf("trace error: %s", err)
+		}
+		leaves := make([]string, 0)
+		for _, v := range visits {
+			if v.Node.Arr != nil {
+				continue
+			}
+			leaf := fmt.Sprintf("%s:%d:%s", v.Node.Print(), v.Negates, v.Moves)
+			if flips := flipsString(v.Flips); flips != "-" {
+				leaf = fmt.Sprintf("%s:%d:%s:%s", v.Node.Print(), v.Negates, flips, v.Moves)
+			}
+			leaves = append(leaves, leaf)
+		}
+		if got := strings.Join(leaves, " "); got != t.leaves {
+			return fmt.Errorf("trace of %s should visit %s, not %s", t.expr, t.leaves, got)
+		}
+	}
+	// generators that are not faces can be turned any number of times
+	fmt.Fprintf(w, "checkSolve: lastlayer -gen [fr],u after [fr]3\n")
+	scrambled := NewCube()
+	if err := scrambled.Apply("[fr]3"); err != nil {
+		return fmt.Errorf("scramble error: %s", err)
+	}
+	found, err := scrambled.Solve(masks["lastlayer"], "[fr],u", 6)
+	if err == nil {
+		err = scrambled.Apply(found)
+	}
+	if err != nil {
+		return fmt.Errorf("solve error on lastlayer -gen [fr],u: %s", err)
+	}
+	if !scrambled.Solved() {
+		return fmt.Errorf("solve lastlayer -gen [fr],u did not solve [fr]3 with %s", found)
+	}
+	// a clone turns on its own, and states compare the way the cubes do
+	fmt.Fprintf(w, "checkState: clone, undo and rotation\n")
+	turned := NewCube()
+	if err := turned.Apply("[fr]3"); err != nil {
+		return fmt.Errorf("state error: %s", err)
+	}
+	clone := turned.Clone()
+	if err := clone.Apply("URF"); err != nil {
+		return fmt.Errorf("state error: %s", err)
+	}
+	if clone.Equal(turned) || !clone.EqualUpToRotation(turned) || !turned.EqualUpToRotation(clone) {
+		return fmt.Errorf("a turned clone of [fr]3 should only be equal up to rotation")
+	}
+	if NewCube().EqualUpToRotation(turned) {
+		return fmt.Errorf("[fr]3 should not be equal to a new cube up to rotation")
+	}
+	clone.Pop()
+	if !clone.Equal(turned) || clone.State() != turned.State() {
+		return fmt.Errorf("undo of a clone should be equal to the cube it was cloned from")
+	}
+	fmt.Fprintf(w, "post test complete\n\n")
+	return nil
+}
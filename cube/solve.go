@@ -0,0 +1,258 @@
+package cube
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+  Solving a mask is an iterative deepening search (IDA*), that prunes
+  with tables of how far some of the masked pieces are from solved.
+
+  A table only looks at a few pieces, by giving their stickers labels,
+  and ignoring everything else. A move does the same thing to labels as it
+  does to stickers, so a table is made by turning the labels of a solved
+  cube until every arrangement has been seen. The bound from a table never
+  overestimates, so the first solution found is a shortest one.
+
+  Pieces that share a class must be in the same table, or the table would
+  think that only one of the interchangeable stickers is solved.
+*/
+
+// the biggest table that we are willing to make
+const pruneTableMax = 500000
+
+// how many positions a solve looks at before giving up, which is some seconds
+const solveNodeMax = 5000000
+
+type pruneTable struct {
+	// Label is nonzero for original stickers that the table looks at
+	Label Perm
+	Dist  map[Perm]uint8
+}
+
+func (t *pruneTable) abstract(state Perm) Perm {
+	var a Perm
+	for i := range state {
+		a[i] = t.Label[state[i]]
+	}
+	return a
+}
+
+// tables that were already made, by the labels and moves used
+var pruneTables = make(map[string]*pruneTable)
+
+// newPruneTable turns the labels of a solved cube in every way that the moves allow
+func newPruneTable(label Perm, moves []move) (*pruneTable, error) {
+	key := fmt.Sprintf("%v %s", label, movesString(moves))
+	if t, ok := pruneTables[key]; ok {
+		return t, nil
+	}
+	t := &pruneTable{Label: label, Dist: map[Perm]uint8{label: 0}}
+	edge := []Perm{label}
+	for d := uint8(1); len(edge) > 0; d++ {
+		next := make([]Perm, 0)
+		for _, a := range edge {
+			for _, m := range moves {
+				b := a.Then(m.Perm)
+				if _, ok := t.Dist[b]; !ok {
+					if len(t.Dist) >= 10*pruneTableMax {
+						return nil, fmt.Errorf("the mask has too many pieces that are interchangeable")
+					}
+					t.Dist[b] = d
+					next = append(next, b)
+				}
+			}
+		}
+		edge = next
+	}
+	pruneTables[key] = t
+	return t, nil
+}
+
+// maskTables splits the masked pieces into tables that are not too big
+func maskTables(mask Mask, moves []move) ([]*pruneTable, error) {
+	classIDs := make(map[string]uint8)
+	for _, k := range mask.Keys() {
+		if _, ok := classIDs[mask.Class[k]]; !ok {
+			classIDs[mask.Class[k]] = uint8(len(classIDs) + 1)
+		}
+	}
+
+	// units are pieces that share classes, and must stay in one table
+	type unit struct {
+		pieces  [][]string
+		classes map[string]bool
+	}
+	units := make([]*unit, 0)
+	seen := make(map[string]bool)
+	for _, k := range mask.Keys() {
+		if len(k) == 1 || seen[k] {
+			continue
+		}
+		piece := pieceOf(k)
+		u := &unit{pieces: [][]string{piece}, classes: make(map[string]bool)}
+		for _, at := range piece {
+			seen[at] = true
+			if class, ok := mask.Class[at]; ok {
+				u.classes[class] = true
+			}
+		}
+		// merge with every unit that shares a class
+		merged := make([]*unit, 0)
+		for _, other := range units {
+			shared := false
+			for class := range other.classes {
+				shared = shared || u.classes[class]
+			}
+			if shared {
+				u.pieces = append(u.pieces, other.pieces...)
+				for class := range other.classes {
+					u.classes[class] = true
+				}
+			} else {
+				merged = append(merged, other)
+			}
+		}
+		units = append(merged, u)
+	}
+	// edges first, so that they go in tables together
+	sort.SliceStable(units, func(i, j int) bool {
+		return len(units[i].pieces[0][0]) < len(units[j].pieces[0][0])
+	})
+
+	// size guesses how many ways the pieces can be arranged, as if they were all different,
+	// but not counting the ways to swap pieces that look the same
+	size := func(pieces [][]string) float64 {
+		n := 1.0
+		placed := map[int]int{2: 0, 3: 0}
+		same := make(map[string]int)
+		for _, piece := range pieces {
+			places := map[int]int{2: 12, 3: 8}[len(piece)]
+			n *= float64((places - placed[len(piece)]) * len(piece))
+			placed[len(piece)]++
+			classes := ""
+			for _, at := range piece {
+				classes += mask.Class[at] + " "
+			}
+			same[classes]++
+			n /= float64(same[classes])
+		}
+		return n
+	}
+
+	tables := make([]*pruneTable, 0)
+	add := func(pieces [][]string) error {
+		var label Perm
+		for _, piece := range pieces {
+			for _, at := range piece {
+				if class, ok := mask.Class[at]; ok {
+					label[stickerIndex[at]] = classIDs[class]
+				}
+			}
+		}
+		t, err := newPruneTable(label, moves)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, t)
+		return nil
+	}
+	chunk := make([][]string, 0)
+	for _, u := range units {
+		if len(chunk) > 0 && size(append(chunk, u.pieces...)) > pruneTableMax {
+			if err := add(chunk); err != nil {
+				return nil, err
+			}
+			chunk = make([][]string, 0)
+		}
+		chunk = append(chunk, u.pieces...)
+	}
+	if len(chunk) > 0 {
+		if err := add(chunk); err != nil {
+			return nil, err
+		}
+	}
+	return tables, nil
+}
+
+// solveMask finds a shortest list of moves that solves the stickers in the mask,
+// giving up past depth moves, or with an error past solveNodeMax positions.
+func solveMask(start Perm, mask Mask, moves []move, depth int) ([]move, bool, error) {
+	tables, err := maskTables(mask, moves)
+	if err != nil {
+		return nil, false, err
+	}
+	// a lower bound on moves to solve, which is too big when it can not be solved
+	bound := func(s Perm) int {
+		h := 0
+		for _, t := range tables {
+			d, ok := t.Dist[t.abstract(s)]
+			if !ok {
+				return depth + 1
+			}
+			if int(d) > h {
+				h = int(d)
+			}
+		}
+		return h
+	}
+
+	var found []move
+	nodes := 0
+	var dfs func(s Perm, path []move, limit int) bool
+	dfs = func(s Perm, path []move, limit int) bool {
+		nodes++
+		if nodes > solveNodeMax {
+			return false
+		}
+		h := bound(s)
+		if h == 0 && mask.Solved(s) {
+			found = append([]move(nil), path...)
+			return true
+		}
+		if len(path)+h > limit {
+			return false
+		}
+		for _, m := range moves {
+			if canFollow(path, m) && dfs(s.Then(m.Perm), append(path, m), limit) {
+				return true
+			}
+		}
+		return false
+	}
+	for limit := bound(start); limit <= depth; limit++ {
+		if dfs(start, nil, limit) {
+			return found, true, nil
+		}
+		if nodes > solveNodeMax {
+			return nil, false, fmt.Errorf("%s is too deep, solve a smaller mask first, like cross or f2l-fr", mask.Name)
+		}
+	}
+	return nil, false, nil
+}
+
+// Solve finds the shortest moves that solve the stickers in the mask.
+func (cube *Cube) Solve(mask Mask, gen string, depth int) (string, error) {
+	for _, f := range cube.Faces {
+		if cube.Stickers[f] != f {
+			return "", fmt.Errorf("turn the whole cube back, so that the %s center is in %s", f, f)
+		}
+	}
+	state, err := cube.Tracking()
+	if err != nil {
+		return "", err
+	}
+	moves, err := genMoves(gen)
+	if err != nil {
+		return "", err
+	}
+	found, ok, err := solveMask(state, mask, moves, depth)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("%s can not be solved within %d moves", mask.Name, depth)
+	}
+	return movesString(found), nil
+}
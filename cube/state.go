@@ -0,0 +1,177 @@
+package cube
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+  The state of a cube, in the forms that programs compare and print:
+  facelets are the 9 stickers of every face, as a person reads them off
+  the cube, and the period is how many times the moves that made it can
+  be made before it is new again.
+
+  A State is the colors of all 54 stickers as one value, a letter each in
+  the order of StickerKeys. It can be compared with ==, and used as a map
+  key to remember positions that a search has visited. History keeps one
+  for every command, so that p can put the stickers back.
+*/
+
+// State is the colors of the stickers, in the order of StickerKeys
+type State [StickerCount]byte
+
+// StateOf is the state of a map of stickers
+func StateOf(stickers map[string]string) State {
+	var s State
+	for i, k := range StickerKeys {
+		if v := stickers[k]; v != "" {
+			s[i] = v[0]
+		}
+	}
+	return s
+}
+
+// State is the colors of the cube as it is now
+func (cube *Cube) State() State {
+	return StateOf(cube.Stickers)
+}
+
+// SetState colors the cube the way it was when the state was taken
+func (cube *Cube) SetState(s State) {
+	for i, k := range StickerKeys {
+		cube.Stickers[k] = string(rune(s[i]))
+	}
+}
+
+// String is the colors, one letter per sticker
+func (s State) String() string {
+	return string(s[:])
+}
+
+// Clone is a copy of the cube, with its history, that can be turned without
+// changing this one. Trace and Visit are not copied.
+func (cube *Cube) Clone() *Cube {
+	clone := NewCube()
+	clone.Stickers = CopyStickers(cube.Stickers)
+	clone.History = append([]State(nil), cube.History...)
+	if cube.Before != nil {
+		before := *cube.Before
+		clone.Before = &before
+	}
+	return clone
+}
+
+// Equal is true when every sticker has the same color on both cubes
+func (cube *Cube) Equal(other *Cube) bool {
+	return cube.State() == other.State()
+}
+
+// EqualUpToRotation is true when turning the whole cube some way makes it
+// equal to the other cube
+func (cube *Cube) EqualUpToRotation(other *Cube) bool {
+	from, to := cube.State(), other.State()
+	for _, p := range rotations {
+		if from.Permuted(p) == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Permuted is the state after the stickers are moved by p
+func (s State) Permuted(p Perm) State {
+	var r State
+	for i := range r {
+		r[i] = s[p[i]]
+	}
+	return r
+}
+
+// rotations are the 24 ways to hold the cube, as whole cube turns
+var rotations []Perm
+
+// makeRotations finds every permutation that U and R turns can make
+func makeRotations() error {
+	gens := make([]Perm, 0, 2)
+	for _, turn := range []string{"U", "R"} {
+		node, err := NewCube().Parse(turn)
+		if err != nil {
+			return err
+		}
+		p, err := NewCube().PermOf(node)
+		if err != nil {
+			return err
+		}
+		gens = append(gens, p)
+	}
+	seen := map[Perm]bool{IdentityPerm(): true}
+	rotations = []Perm{IdentityPerm()}
+	for i := 0; i < len(rotations); i++ {
+		for _, g := range gens {
+			p := rotations[i].Then(g)
+			if !seen[p] {
+				seen[p] = true
+				rotations = append(rotations, p)
+			}
+		}
+	}
+	if len(rotations) != 24 {
+		return fmt.Errorf("expected 24 rotations, got %d", len(rotations))
+	}
+	return nil
+}
+
+// FaceKeys are the stickers of every face, as they read when the face is
+// turned to the front with u up, and b is turned around y to get there
+var FaceKeys = [][]string{
+	{"ulb", "ub", "ubr", "ul", "u", "ur", "ufl", "uf", "urf"},
+	{"rfu", "ru", "rub", "rf", "r", "rb", "rdf", "rd", "rbd"},
+	{"flu", "fu", "fur", "fl", "f", "fr", "fdl", "fd", "frd"},
+	{"dlf", "df", "dfr", "dl", "d", "dr", "dbl", "db", "drb"},
+	{"lbu", "lu", "luf", "lb", "l", "lf", "ldb", "ld", "lfd"},
+	{"bru", "bu", "bul", "br", "b", "bl", "bdr", "bd", "bld"},
+}
+
+// Facelets is the colors of the u r f d l b faces, 9 letters per face
+func (cube *Cube) Facelets() string {
+	faces := make([]string, 0, len(FaceKeys))
+	for _, keys := range FaceKeys {
+		face := ""
+		for _, k := range keys {
+			face += cube.Stickers[k]
+		}
+		faces = append(faces, face)
+	}
+	return strings.Join(faces, " ")
+}
+
+// Period is how many times the moves that made this cube from a new one
+// can be made before it is new again
+func (cube *Cube) Period() (int, error) {
+	p, err := cube.Tracking()
+	if err != nil {
+		return 0, err
+	}
+	return p.Order(), nil
+}
+
+// Solved is true when every face is one color, whichever way the cube is turned
+func (cube *Cube) Solved() bool {
+	for _, keys := range FaceKeys {
+		for _, k := range keys {
+			if cube.Stickers[k] != cube.Stickers[keys[4]] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CopyStickers is a copy that can be changed without changing the cube
+func CopyStickers(stickers map[string]string) map[string]string {
+	copied := make(map[string]string, len(stickers))
+	for k, v := range stickers {
+		copied[k] = v
+	}
+	return copied
+}
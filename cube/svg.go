@@ -0,0 +1,101 @@
+package cube
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+/*
+  The svg export draws the same unfolded cube as Draw, from NetRows,
+  so that pictures for docs do not have to be terminal screenshots.
+*/
+
+// sizes in pixels
+const svgCell = 20
+const svgGap = 6
+const svgBlank = 10
+const svgMargin = 10
+
+// svgNetSize is how big one cube is
+func svgNetSize() (int, int) {
+	w := 0
+	for i := range NetRows[0] {
+		if netGap(i) {
+			w += svgGap
+		}
+		w += svgCell
+	}
+	h := 0
+	for _, row := range NetRows {
+		if row == nil {
+			h += svgBlank
+		} else {
+			h += svgCell
+		}
+	}
+	return w, h
+}
+
+// SVG draws the cube as an svg image, in the colors of NewStyle
+func (cube *Cube) SVG(w io.Writer) error {
+	return NewStyle().SVG(w, cube)
+}
+
+// SVG draws the cube as an svg image
+func (style *Style) SVG(w io.Writer, cube *Cube) error {
+	return style.DrawSVG(w, []*Cube{cube}, "")
+}
+
+// DrawSVG draws cubes side by side, with a caption under them if it is not empty
+func (style *Style) DrawSVG(w io.Writer, cubes []*Cube, caption string) error {
+	netW, netH := svgNetSize()
+	width := 2*svgMargin + len(cubes)*netW + (len(cubes)-1)*2*svgCell
+	height := 2*svgMargin + netH
+	if caption != "" {
+		height += svgCell
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", width, height, width, height)
+	fmt.Fprintf(&b, "<rect width=\"%d\" height=\"%d\" fill=\"#202020\"/>\n", width, height)
+	for c, cube := range cubes {
+		cube = style.Shown(cube)
+		left := svgMargin + c*(netW+2*svgCell)
+		y := svgMargin
+		for _, row := range NetRows {
+			if row == nil {
+				y += svgBlank
+				continue
+			}
+			x := left
+			for i, k := range row {
+				if netGap(i) {
+					x += svgGap
+				}
+				if k != "" {
+					v := cube.Stickers[k]
+					if style.stickerRGB(v) == "" {
+						return fmt.Errorf("sticker is not mapped: %s", k)
+					}
+					fmt.Fprintf(&b,
+						"<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" rx=\"3\" fill=\"%s\" stroke=\"#000000\"><title>%s</title></rect>\n",
+						x+1, y+1, svgCell-2, svgCell-2, style.stickerRGB(v), k,
+					)
+				}
+				x += svgCell
+			}
+			y += svgCell
+		}
+	}
+	if caption != "" {
+		fmt.Fprintf(&b,
+			"<text x=\"%d\" y=\"%d\" font-family=\"monospace\" font-size=\"14\" fill=\"#ffffff\">%s</text>\n",
+			svgMargin, height-svgMargin, html.EscapeString(caption),
+		)
+	}
+	fmt.Fprintf(&b, "</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,181 @@
+package cube
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+  tree prints the Node that an expression parses to, and trace prints
+  every node that Execute visits, in the order that it visits them. A node
+  is visited more than once when it repeats, or when a commutator runs it
+  again negated. For every visit, trace says:
+
+    negates  how many negations it is under, counting its own
+    flips    the reflections it is under
+    moves    the moves that it emitted
+
+  A leaf with an odd number of negates and flips together turns backwards.
+  Both can print json instead of text, for tools.
+*/
+
+// Visit is what Execute did at a node
+type Visit struct {
+	Seq     int
+	Path    []int
+	Node    Node
+	Negates int
+	Flips   [4]int
+	Moves   string
+}
+
+// nodeKind names the kind of a node for people
+func nodeKind(node Node) string {
+	switch {
+	case node.Arr == nil:
+		if strings.ToUpper(node.Face) == node.Face {
+			return "cube turn"
+		}
+		return "face turn"
+	case node.Commutator && node.Conjugated:
+		return "conjugate"
+	case node.Commutator:
+		return "commutator"
+	}
+	return "group"
+}
+
+// nodeAttributes are the things about a node that change how it runs
+func nodeAttributes(node Node) []string {
+	attrs := make([]string, 0)
+	if node.Negate {
+		attrs = append(attrs, "negated")
+	}
+	if node.Repeat > 1 {
+		attrs = append(attrs, fmt.Sprintf("repeat %d", node.Repeat))
+	}
+	if node.Reflection != "" {
+		attrs = append(attrs, "reflect "+node.Reflection)
+	}
+	return attrs
+}
+
+// TreeText is the node as an indented tree
+func TreeText(node Node) string {
+	var b strings.Builder
+	var walk func(node Node, depth int)
+	walk = func(node Node, depth int) {
+		line := fmt.Sprintf("%s%s", strings.Repeat("  ", depth), node.Print())
+		fmt.Fprintf(&b, "%-30s %s", line, nodeKind(node))
+		if attrs := nodeAttributes(node); len(attrs) > 0 {
+			fmt.Fprintf(&b, ", %s", strings.Join(attrs, ", "))
+		}
+		b.WriteString("\n")
+		for _, n := range node.Arr {
+			walk(n, depth+1)
+		}
+	}
+	walk(node, 0)
+	return b.String()
+}
+
+// Tree is a node as json, with its children nested in it
+type Tree struct {
+	Text       string `json:"text"`
+	Kind       string `json:"kind"`
+	Face       string `json:"face,omitempty"`
+	Negate     bool   `json:"negate,omitempty"`
+	Repeat     int    `json:"repeat,omitempty"`
+	Reflection string `json:"reflection,omitempty"`
+	Children   []Tree `json:"children,omitempty"`
+}
+
+// TreeOf is the tree of a node
+func TreeOf(node Node) Tree {
+	t := Tree{
+		Text:       node.Print(),
+		Kind:       nodeKind(node),
+		Face:       node.Face,
+		Negate:     node.Negate,
+		Repeat:     node.Repeat,
+		Reflection: node.Reflection,
+	}
+	for _, n := range node.Arr {
+		t.Children = append(t.Children, TreeOf(n))
+	}
+	return t
+}
+
+// TreeJSON is the node as nested json objects
+func TreeJSON(node Node) (string, error) {
+	out, err := json.MarshalIndent(TreeOf(node), "", "  ")
+	return string(out), err
+}
+
+// TraceVisits executes a node on a copy of the cube, and lists the visits
+// in the order that they started
+func (cube *Cube) TraceVisits(node Node) ([]Visit, error) {
+	scratch := cube.Clone()
+	visits := make([]Visit, 0)
+	scratch.Visit = func(v Visit) {
+		visits = append(visits, v)
+	}
+	_, err := scratch.Execute(node, 0, 0, 0, 0, 0)
+	sort.Slice(visits, func(i, j int) bool { return visits[i].Seq < visits[j].Seq })
+	return visits, err
+}
+
+// flipsString is like x1 z1, or - for none
+func flipsString(flips [4]int) string {
+	words := make([]string, 0)
+	for i, axis := range []string{"x", "y", "z", "w"} {
+		if flips[i] != 0 {
+			words = append(words, fmt.Sprintf("%s%d", axis, flips[i]))
+		}
+	}
+	if len(words) == 0 {
+		return "-"
+	}
+	return strings.Join(words, " ")
+}
+
+// TraceText is a line per visit, indented by depth
+func TraceText(visits []Visit) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-30s %-7s %-7s %s\n", "node", "negates", "flips", "moves")
+	for _, v := range visits {
+		line := strings.Repeat("  ", len(v.Path)) + v.Node.Print()
+		fmt.Fprintf(&b, "%-30s %-7d %-7s %s\n", line, v.Negates, flipsString(v.Flips), v.Moves)
+	}
+	return b.String()
+}
+
+type visitJSON struct {
+	Seq     int            `json:"seq"`
+	Path    []int          `json:"path"`
+	Text    string         `json:"text"`
+	Kind    string         `json:"kind"`
+	Negates int            `json:"negates"`
+	Flips   map[string]int `json:"flips"`
+	Moves   []string       `json:"moves"`
+}
+
+// TraceJSON is a json array with an object per visit
+func TraceJSON(visits []Visit) (string, error) {
+	out := make([]visitJSON, 0, len(visits))
+	for _, v := range visits {
+		out = append(out, visitJSON{
+			Seq:     v.Seq,
+			Path:    v.Path,
+			Text:    v.Node.Print(),
+			Kind:    nodeKind(v.Node),
+			Negates: v.Negates,
+			Flips:   map[string]int{"x": v.Flips[0], "y": v.Flips[1], "z": v.Flips[2], "w": v.Flips[3]},
+			Moves:   strings.Fields(v.Moves),
+		})
+	}
+	text, err := json.MarshalIndent(out, "", "  ")
+	return string(text), err
+}
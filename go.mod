@@ -0,0 +1,3 @@
+module github.com/rfielding/rustCube
+
+go 1.22